@@ -0,0 +1,141 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+// alignedLine describes how the logical column of each rune on a visual
+// line must be shifted in order to honor the configured text alignment.
+type alignedLine struct {
+	// offset is added to the logical column of every rune on the line.
+	// Used for AlignRight and AlignCenter.
+	offset int
+	// extraBefore maps the byte position of a rune to the cumulative number
+	// of extra spaces that must have been inserted by the time that rune is
+	// reached. Used for Justify, which distributes extra space across the
+	// gaps between words.
+	extraBefore map[int]int
+}
+
+// lineAlignGap is a run of one or more space runes found between two words
+// on a line.
+type lineAlignGap struct {
+	// pos is the byte position of the first rune following the gap, i.e.
+	// where the extra spaces for that gap are inserted.
+	pos int
+	// size is the number of space runes making up the gap.
+	size int
+}
+
+// computeLineAlign analyzes the visual line of text in the byte range [low,
+// high) and returns how it must be shifted to honor opts.alignMode. isParaEnd
+// indicates that the line is the last visual line of its paragraph, which is
+// always exempt from justification; see isParagraphEnd.
+func computeLineAlign(text string, low, high, width int, opts *options, isParaEnd bool) alignedLine {
+	if opts.alignMode == alignLeft || high <= low {
+		return alignedLine{}
+	}
+
+	line := text[low:high]
+	if n := len(line); n > 0 && line[n-1] == '\n' { // The newline itself occupies no column.
+		line = line[:n-1]
+	}
+	contentWidth := 0
+	for _, r := range line {
+		contentWidth += runeWidth(r)
+	}
+
+	var gaps []lineAlignGap
+	inGap := false
+	for i, r := range line {
+		if r == ' ' {
+			if !inGap {
+				gaps = append(gaps, lineAlignGap{pos: low + i})
+				inGap = true
+			}
+			gaps[len(gaps)-1].size++
+		} else {
+			if inGap {
+				gaps[len(gaps)-1].pos = low + i
+			}
+			inGap = false
+		}
+	}
+	if inGap && len(gaps) > 0 { // A trailing run of spaces is never justified.
+		last := gaps[len(gaps)-1]
+		contentWidth -= last.size
+		gaps = gaps[:len(gaps)-1]
+	}
+	if len(line) > 0 && line[0] == ' ' && len(gaps) > 0 { // Nor is a leading one.
+		contentWidth -= gaps[0].size
+		gaps = gaps[1:]
+	}
+
+	extraWidth := width - contentWidth
+	if extraWidth <= 0 {
+		return alignedLine{}
+	}
+
+	switch opts.alignMode {
+	case alignRight:
+		return alignedLine{offset: extraWidth}
+
+	case alignCenter:
+		return alignedLine{offset: extraWidth / 2}
+
+	case alignJustify:
+		if isParaEnd || len(gaps) == 0 {
+			return alignedLine{}
+		}
+		extraBefore := make(map[int]int, len(gaps))
+		per := extraWidth / len(gaps)
+		rem := extraWidth % len(gaps)
+		var running int
+		for idx, gap := range gaps {
+			n := per
+			if idx < rem { // Front-load the remainder across the first gaps.
+				n++
+			}
+			running += n
+			extraBefore[gap.pos] = running
+		}
+		return alignedLine{extraBefore: extraBefore}
+
+	default:
+		return alignedLine{}
+	}
+}
+
+// runeWidth returns the number of cells r occupies when drawn, matching the
+// width cvs.SetCell reports for it elsewhere in the draw path. Most runes
+// occupy a single cell; the ranges below are the double-width East Asian
+// Wide and Fullwidth blocks (Unicode Standard Annex #11), which are the only
+// runes commonly drawn that occupy two.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x1100:
+		return 1
+	case r <= 0x115f, // Hangul Jamo.
+		r == 0x2329 || r == 0x232a,
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f, // CJK Radicals through Yi.
+		r >= 0xac00 && r <= 0xd7a3,                // Hangul Syllables.
+		r >= 0xf900 && r <= 0xfaff,                // CJK Compatibility Ideographs.
+		r >= 0xfe30 && r <= 0xfe6f,                // CJK Compatibility Forms, Small Form Variants.
+		r >= 0xff00 && r <= 0xff60,                // Fullwidth Forms.
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x20000 && r <= 0x3fffd: // CJK Unified Ideographs Extension B and beyond.
+		return 2
+	default:
+		return 1
+	}
+}
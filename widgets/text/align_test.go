@@ -0,0 +1,288 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "testing"
+
+func TestComputeLineAlign(t *testing.T) {
+	tests := []struct {
+		desc        string
+		text        string
+		width       int
+		opt         Option
+		isParaEnd   bool
+		wantOffset  int
+		wantExtraAt map[int]int
+	}{
+		{
+			desc:       "left alignment is a no-op",
+			text:       "hello",
+			width:      10,
+			opt:        AlignLeft(),
+			wantOffset: 0,
+		},
+		{
+			desc:       "right alignment offsets by the leftover width",
+			text:       "hello",
+			width:      10,
+			opt:        AlignRight(),
+			wantOffset: 5,
+		},
+		{
+			desc:       "center alignment offsets by half the leftover width",
+			text:       "hello",
+			width:      11,
+			opt:        AlignCenter(),
+			wantOffset: 3,
+		},
+		{
+			desc:       "line already spans the full width",
+			text:       "0123456789",
+			width:      10,
+			opt:        AlignRight(),
+			wantOffset: 0,
+		},
+		{
+			desc:       "double-width runes count for two cells each, not one",
+			text:       "中文", // Two double-width runes, four cells wide.
+			width:      10,
+			opt:        AlignRight(),
+			wantOffset: 6,
+		},
+		{
+			desc:      "justify distributes extra space across a single gap",
+			text:      "foo bar",
+			width:     11,
+			opt:       Justify(),
+			isParaEnd: false,
+			wantExtraAt: map[int]int{
+				4: 4, // Position of 'b' in "bar", after the gap.
+			},
+		},
+		{
+			desc:      "justify skips the last line of a paragraph",
+			text:      "foo bar",
+			width:     11,
+			opt:       Justify(),
+			isParaEnd: true,
+		},
+		{
+			desc:      "justify never expands leading or trailing gaps",
+			text:      " foo bar ",
+			width:     13,
+			opt:       Justify(),
+			isParaEnd: false,
+			wantExtraAt: map[int]int{
+				5: 6, // Position of 'b' in "bar", after the inner gap.
+			},
+		},
+		{
+			desc:      "justify front-loads the remainder across gaps",
+			text:      "a b c",
+			width:     10,
+			opt:       Justify(),
+			isParaEnd: false,
+			wantExtraAt: map[int]int{
+				2: 3, // "a" -> gap -> "b": 3 extra spaces (ceil(5/2)).
+				4: 5, // "b" -> gap -> "c": cumulative 5 extra spaces.
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := newOptions(tc.opt)
+			got := computeLineAlign(tc.text, 0, len(tc.text), tc.width, opts, tc.isParaEnd)
+			if got.offset != tc.wantOffset {
+				t.Errorf("computeLineAlign(%q).offset => %d, want %d", tc.text, got.offset, tc.wantOffset)
+			}
+			if len(got.extraBefore) != len(tc.wantExtraAt) {
+				t.Fatalf("computeLineAlign(%q).extraBefore => %v, want %v", tc.text, got.extraBefore, tc.wantExtraAt)
+			}
+			for pos, want := range tc.wantExtraAt {
+				if got.extraBefore[pos] != want {
+					t.Errorf("computeLineAlign(%q).extraBefore[%d] => %d, want %d", tc.text, pos, got.extraBefore[pos], want)
+				}
+			}
+		})
+	}
+}
+
+// TestComputeLineAlignAcrossWrapModes drives computeLineAlign through the
+// same findLines/lineBounds/isParagraphEnd pipeline Draw uses, for every
+// combination of alignment and wrap mode, to verify that wrapping a
+// paragraph onto multiple visual lines changes which lines get aligned (the
+// last visual line of a paragraph is never justified) without disturbing
+// alignment under Trim or HScroll, which never split a line in two.
+func TestComputeLineAlignAcrossWrapModes(t *testing.T) {
+	const text = "foo bar baz" // 11 cols of content, one paragraph, no trailing newline.
+
+	tests := []struct {
+		desc          string
+		wrapOpt       Option
+		alignOpt      Option
+		width         int
+		wantOffsets   []int  // One entry per visual line found by findLines.
+		wantJustified []bool // Whether extraBefore is non-empty, per visual line.
+	}{
+		{
+			desc:          "trim + left is a no-op regardless of width",
+			wrapOpt:       Trim(),
+			alignOpt:      AlignLeft(),
+			width:         20,
+			wantOffsets:   []int{0},
+			wantJustified: []bool{false},
+		},
+		{
+			desc:          "trim + right offsets the single visual line",
+			wrapOpt:       Trim(),
+			alignOpt:      AlignRight(),
+			width:         20,
+			wantOffsets:   []int{9}, // width(20) - len(text)(11).
+			wantJustified: []bool{false},
+		},
+		{
+			desc:          "trim + center offsets the single visual line by half",
+			wrapOpt:       Trim(),
+			alignOpt:      AlignCenter(),
+			width:         21,
+			wantOffsets:   []int{5}, // (width(21) - 11) / 2.
+			wantJustified: []bool{false},
+		},
+		{
+			desc:          "trim + justify never applies, the only visual line is also the last of its paragraph",
+			wrapOpt:       Trim(),
+			alignOpt:      Justify(),
+			width:         15,
+			wantOffsets:   []int{0},
+			wantJustified: []bool{false},
+		},
+		{
+			desc:          "hscroll + right behaves like trim, never wrapping",
+			wrapOpt:       HScroll(),
+			alignOpt:      AlignRight(),
+			width:         20,
+			wantOffsets:   []int{9},
+			wantJustified: []bool{false},
+		},
+		{
+			desc:          "hscroll + right is a no-op once the line overflows the width",
+			wrapOpt:       HScroll(),
+			alignOpt:      AlignRight(),
+			width:         5,
+			wantOffsets:   []int{0}, // Line is wider than the canvas, nothing to pad.
+			wantJustified: []bool{false},
+		},
+		{
+			desc:     "wrap + right aligns each wrapped visual line independently",
+			wrapOpt:  Wrap(),
+			alignOpt: AlignRight(),
+			width:    4, // Wraps onto "foo ", "bar " and "baz".
+			// Each of "foo " and "bar " has a trailing gap that is never
+			// counted towards content width (even outside of Justify), so
+			// their 1-cell offset comes from that trailing space, not from
+			// leftover line width; "baz" is 3 cells wide in a 4-wide canvas.
+			wantOffsets:   []int{1, 1, 1},
+			wantJustified: []bool{false, false, false},
+		},
+		{
+			desc:     "wrap + justify finds no internal gaps left to distribute",
+			wrapOpt:  Wrap(),
+			alignOpt: Justify(),
+			width:    4,
+			// "foo " and "bar " each have only the trailing gap, which is
+			// never justified; "baz" is the paragraph end and is exempt too.
+			wantOffsets:   []int{0, 0, 0},
+			wantJustified: []bool{false, false, false},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := newOptions(tc.wrapOpt, tc.alignOpt)
+			lines, _ := findLines(text, tc.width, opts)
+			if len(lines) != len(tc.wantOffsets) {
+				t.Fatalf("findLines(%q, %d) => %d visual lines %v, want %d", text, tc.width, len(lines), lines, len(tc.wantOffsets))
+			}
+			for ln := range lines {
+				low, high := lineBounds(lines, ln, len(text))
+				isEnd := isParagraphEnd(text, lines, ln)
+				got := computeLineAlign(text, low, high, tc.width, opts, isEnd)
+				if got.offset != tc.wantOffsets[ln] {
+					t.Errorf("visual line %d: computeLineAlign().offset => %d, want %d", ln, got.offset, tc.wantOffsets[ln])
+				}
+				if gotJustified := len(got.extraBefore) > 0; gotJustified != tc.wantJustified[ln] {
+					t.Errorf("visual line %d: computeLineAlign() justified => %v, want %v", ln, gotJustified, tc.wantJustified[ln])
+				}
+			}
+		})
+	}
+}
+
+// TestAlignOffsetInteractsWithHScroll verifies the combination draw() relies
+// on: an aligned line's offset is added to a rune's logical column before
+// lineTrim applies the horizontal scroll offset, so that scrolling right
+// eventually reveals a right-aligned or centered line's leading padding
+// being scrolled out of view, exactly like any other content.
+func TestAlignOffsetInteractsWithHScroll(t *testing.T) {
+	const (
+		text  = "hi\n" // Width 2, much narrower than the canvas.
+		width = 10
+	)
+	opts := newOptions(HScroll(), AlignRight())
+	align := computeLineAlign(text, 0, len(text), width, opts, true /* isParaEnd */)
+	if want := width - 2; align.offset != want {
+		t.Fatalf("computeLineAlign().offset => %d, want %d", align.offset, want)
+	}
+
+	tests := []struct {
+		desc        string
+		rawCol      int
+		xOffset     int
+		wantTrimmed bool
+		wantCol     int
+	}{
+		{
+			desc:    "first rune of the aligned line is visible without scrolling",
+			rawCol:  0,
+			xOffset: 0,
+			wantCol: align.offset, // Pushed to the right by the alignment offset.
+		},
+		{
+			desc:    "scrolling right by exactly the offset brings the rune to column 0",
+			rawCol:  0,
+			xOffset: align.offset,
+			wantCol: 0,
+		},
+		{
+			desc:        "scrolling right past the offset trims the rune out of view",
+			rawCol:      0,
+			xOffset:     align.offset + 1,
+			wantTrimmed: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := lineTrim(tc.rawCol+align.offset, width, tc.xOffset)
+			if got.trimmed != tc.wantTrimmed {
+				t.Fatalf("lineTrim() trimmed => %v, want %v", got.trimmed, tc.wantTrimmed)
+			}
+			if !got.trimmed && got.col != tc.wantCol {
+				t.Errorf("lineTrim() col => %d, want %d", got.col, tc.wantCol)
+			}
+		})
+	}
+}
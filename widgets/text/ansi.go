@@ -0,0 +1,271 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mum4k/termdash/cell"
+)
+
+// styleRange assigns cell options to the byte range [low, high) of text
+// that has already had its ANSI escape sequences stripped, i.e. the text as
+// it will be stored in the buffer.
+type styleRange struct {
+	low  int
+	high int
+	opts []cell.Option
+}
+
+// ansiState accumulates the SGR (Select Graphic Rendition) attributes in
+// effect while scanning ANSI-escaped text.
+type ansiState struct {
+	bold      bool
+	underline bool
+	reverse   bool
+	fg        *cell.Color
+	bg        *cell.Color
+}
+
+// reset clears every attribute, as SGR code 0 (or a bare "ESC[m") does.
+func (s *ansiState) reset() {
+	*s = ansiState{}
+}
+
+// apply updates the state according to one SGR parameter code. fields are
+// any parameters following code in the same sequence, needed by the
+// extended color codes 38 and 48. Returns the number of additional entries
+// of fields consumed, and false if code required extended color parameters
+// that turned out to be malformed, in which case the rest of the sequence
+// must be abandoned.
+func (s *ansiState) apply(code int, fields []string) (consumed int, ok bool) {
+	switch {
+	case code == 0:
+		s.reset()
+	case code == 1:
+		s.bold = true
+	case code == 4:
+		s.underline = true
+	case code == 7:
+		s.reverse = true
+	case code == 22:
+		s.bold = false
+	case code == 24:
+		s.underline = false
+	case code == 27:
+		s.reverse = false
+	case code == 39:
+		s.fg = nil
+	case code == 49:
+		s.bg = nil
+	case code >= 30 && code <= 37:
+		c := cell.ColorNumber(code - 30)
+		s.fg = &c
+	case code >= 40 && code <= 47:
+		c := cell.ColorNumber(code - 40)
+		s.bg = &c
+	case code >= 90 && code <= 97:
+		c := cell.ColorNumber(code - 90 + 8)
+		s.fg = &c
+	case code >= 100 && code <= 107:
+		c := cell.ColorNumber(code - 100 + 8)
+		s.bg = &c
+	case code == 38 || code == 48:
+		c, n, extOK := parseExtendedColor(fields)
+		if !extOK {
+			return 0, false
+		}
+		if code == 38 {
+			s.fg = &c
+		} else {
+			s.bg = &c
+		}
+		return n, true
+	}
+	return 0, true
+}
+
+// cellOpts translates the current state into cell options, in a stable
+// order. Returns nil if no attribute is set, i.e. the state is equivalent
+// to a reset.
+func (s *ansiState) cellOpts() []cell.Option {
+	var opts []cell.Option
+	if s.bold {
+		opts = append(opts, cell.Bold())
+	}
+	if s.underline {
+		opts = append(opts, cell.Underline())
+	}
+	if s.reverse {
+		opts = append(opts, cell.Reverse())
+	}
+	if s.fg != nil {
+		opts = append(opts, cell.FgColor(*s.fg))
+	}
+	if s.bg != nil {
+		opts = append(opts, cell.BgColor(*s.bg))
+	}
+	return opts
+}
+
+// parseExtendedColor parses the parameters following an SGR 38 (foreground)
+// or 48 (background) code, i.e. either "5;N" (the 256-color palette) or
+// "2;R;G;B" (truecolor). Returns the parsed color, the number of entries of
+// fields consumed, and whether the parameters were well-formed.
+func parseExtendedColor(fields []string) (cell.Color, int, bool) {
+	if len(fields) == 0 {
+		return cell.Color{}, 0, false
+	}
+	switch fields[0] {
+	case "5":
+		if len(fields) < 2 {
+			return cell.Color{}, 0, false
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return cell.Color{}, 0, false
+		}
+		return cell.ColorNumber(n), 2, true
+
+	case "2":
+		if len(fields) < 4 {
+			return cell.Color{}, 0, false
+		}
+		r, errR := strconv.Atoi(fields[1])
+		g, errG := strconv.Atoi(fields[2])
+		b, errB := strconv.Atoi(fields[3])
+		if errR != nil || errG != nil || errB != nil {
+			return cell.Color{}, 0, false
+		}
+		return cell.ColorRGB(uint8(r), uint8(g), uint8(b)), 4, true
+
+	default:
+		return cell.Color{}, 0, false
+	}
+}
+
+// csiSeq attempts to parse a CSI (Control Sequence Introducer) escape
+// sequence at the start of s, where s[0] must be ESC (0x1b). Returns the
+// sequence's parameter bytes (i.e. everything between "ESC[" and the final
+// byte), the final byte identifying the kind of sequence, the number of
+// bytes of s the whole sequence occupies, and whether a valid sequence was
+// found at all.
+func csiSeq(s string) (params string, final byte, n int, ok bool) {
+	if len(s) < 3 || s[0] != 0x1b || s[1] != '[' {
+		return "", 0, 0, false
+	}
+	i := 2
+	for i < len(s) && s[i] >= 0x20 && s[i] <= 0x3f { // Parameter and intermediate bytes.
+		i++
+	}
+	if i >= len(s) || s[i] < 0x40 || s[i] > 0x7e { // Final byte.
+		return "", 0, 0, false
+	}
+	return s[2:i], s[i], i + 1, true
+}
+
+// parseANSIText scans raw for ANSI CSI escape sequences. SGR sequences
+// ("ESC [ params m") are translated into cell options and stripped from the
+// returned text. Any other CSI sequence (e.g. cursor movement or erase) is
+// recognized and silently dropped too, per the documented policy that only
+// styling carries over into the widget; a lone ESC not starting a
+// recognized CSI sequence is left untouched, and will be rejected by the
+// usual validText control-character check like any other control rune.
+//
+// state carries the SGR attributes in effect into and out of the call, so
+// that a caller parsing successive chunks of the same stream (i.e. Write,
+// across separate calls) sees attributes set in one chunk still applied at
+// the start of the next, exactly like a real terminal; StripANSI instead
+// passes a fresh state on every call, since it has no notion of a
+// continuing stream.
+//
+// Returns the text with every recognized escape sequence removed, and the
+// cell options in effect over each byte range of that returned text. Ranges
+// where no attribute is set (e.g. before the first sequence, or after a
+// reset) are omitted.
+func parseANSIText(raw string, state *ansiState) (string, []styleRange) {
+	var (
+		plain  strings.Builder
+		ranges []styleRange
+		curLow = -1 // Start of the current non-empty style, or -1 if none.
+	)
+	if len(state.cellOpts()) > 0 {
+		curLow = 0 // A style already in effect when the call started covers the start of raw too.
+	}
+
+	i := 0
+	for i < len(raw) {
+		next := strings.IndexByte(raw[i:], 0x1b)
+		if next < 0 {
+			plain.WriteString(raw[i:])
+			break
+		}
+		plain.WriteString(raw[i : i+next])
+		i += next
+
+		params, final, n, csiOK := csiSeq(raw[i:])
+		if !csiOK {
+			plain.WriteByte(raw[i])
+			i++
+			continue
+		}
+		i += n
+
+		if final != 'm' {
+			continue // Non-SGR CSI sequence, dropped per policy.
+		}
+
+		pos := plain.Len()
+		if curLow >= 0 && pos > curLow {
+			ranges = append(ranges, styleRange{low: curLow, high: pos, opts: state.cellOpts()})
+		}
+		curLow = -1
+
+		if params == "" {
+			params = "0" // A bare "ESC[m" is equivalent to "ESC[0m".
+		}
+		fields := strings.Split(params, ";")
+		for fi := 0; fi < len(fields); fi++ {
+			code, err := strconv.Atoi(fields[fi])
+			if err != nil {
+				continue // Malformed parameter, skip it.
+			}
+			consumed, applyOK := state.apply(code, fields[fi+1:])
+			if !applyOK {
+				break // Malformed extended color sequence, abandon the rest.
+			}
+			fi += consumed
+		}
+
+		if len(state.cellOpts()) > 0 {
+			curLow = plain.Len()
+		}
+	}
+	if curLow >= 0 && plain.Len() > curLow {
+		ranges = append(ranges, styleRange{low: curLow, high: plain.Len(), opts: state.cellOpts()})
+	}
+
+	return plain.String(), ranges
+}
+
+// StripANSI returns text with every ANSI escape sequence recognized by
+// ParseANSI removed, without applying any of the styling it describes.
+// Useful for callers that want to recover the plain text piped from an
+// external program independently of writing it into the widget.
+func StripANSI(text string) string {
+	plain, _ := parseANSIText(text, &ansiState{})
+	return plain
+}
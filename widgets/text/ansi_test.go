@@ -0,0 +1,187 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "testing"
+
+// wantRange describes the expected byte range and option count of a
+// styleRange. The option count, rather than the options themselves, is
+// compared since cell.Option values aren't comparable.
+type wantRange struct {
+	low, high int
+	numOpts   int
+}
+
+func TestParseANSIText(t *testing.T) {
+	tests := []struct {
+		desc       string
+		raw        string
+		wantPlain  string
+		wantRanges []wantRange
+	}{
+		{
+			desc:      "plain text with no escape sequences",
+			raw:       "hello",
+			wantPlain: "hello",
+		},
+		{
+			desc:      "a single styled run",
+			raw:       "\x1b[1mhello\x1b[0m",
+			wantPlain: "hello",
+			wantRanges: []wantRange{
+				{low: 0, high: 5, numOpts: 1}, // Bold.
+			},
+		},
+		{
+			desc:      "styled text followed by plain text",
+			raw:       "\x1b[1mhi\x1b[0m there",
+			wantPlain: "hi there",
+			wantRanges: []wantRange{
+				{low: 0, high: 2, numOpts: 1},
+			},
+		},
+		{
+			desc:      "reset is not required at the end of the text",
+			raw:       "\x1b[7myo",
+			wantPlain: "yo",
+			wantRanges: []wantRange{
+				{low: 0, high: 2, numOpts: 1}, // Reverse.
+			},
+		},
+		{
+			desc:      "256-color and truecolor foreground",
+			raw:       "\x1b[38;5;196ma\x1b[38;2;1;2;3mb",
+			wantPlain: "ab",
+			wantRanges: []wantRange{
+				{low: 0, high: 1, numOpts: 1},
+				{low: 1, high: 2, numOpts: 1},
+			},
+		},
+		{
+			desc:      "non-SGR CSI sequences are dropped",
+			raw:       "ab\x1b[2Jcd", // Erase display, not an SGR sequence.
+			wantPlain: "abcd",
+		},
+		{
+			desc:      "a lone unrecognized ESC is preserved",
+			raw:       "a\x1bZb",
+			wantPlain: "a\x1bZb",
+		},
+		{
+			desc:      "multiple attributes accumulate on one run",
+			raw:       "\x1b[1m\x1b[4mhi",
+			wantPlain: "hi",
+			wantRanges: []wantRange{
+				{low: 0, high: 2, numOpts: 2}, // Bold and underline.
+			},
+		},
+		{
+			desc:      "default foreground clears the color but keeps other attributes",
+			raw:       "\x1b[1;31mhi\x1b[39mthere",
+			wantPlain: "hithere",
+			wantRanges: []wantRange{
+				{low: 0, high: 2, numOpts: 2}, // Bold and red foreground.
+				{low: 2, high: 7, numOpts: 1}, // Bold only, color reset.
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			gotPlain, gotRanges := parseANSIText(tc.raw, &ansiState{})
+			if gotPlain != tc.wantPlain {
+				t.Errorf("parseANSIText(%q) plain => %q, want %q", tc.raw, gotPlain, tc.wantPlain)
+			}
+			if len(gotRanges) != len(tc.wantRanges) {
+				t.Fatalf("parseANSIText(%q) ranges => %+v, want %+v", tc.raw, gotRanges, tc.wantRanges)
+			}
+			for i, want := range tc.wantRanges {
+				got := gotRanges[i]
+				if got.low != want.low || got.high != want.high {
+					t.Errorf("parseANSIText(%q) ranges[%d] => {%d,%d}, want {%d,%d}", tc.raw, i, got.low, got.high, want.low, want.high)
+				}
+				if len(got.opts) != want.numOpts {
+					t.Errorf("parseANSIText(%q) ranges[%d].opts => %d options, want %d", tc.raw, i, len(got.opts), want.numOpts)
+				}
+			}
+		})
+	}
+}
+
+// TestParseANSITextPersistsStateAcrossCalls verifies that a style set in one
+// call and never reset still applies to the start of the next call when the
+// same state is threaded through, the behavior Text.Write relies on so that
+// a color code sent once by a producer doesn't have to be repeated on every
+// subsequent Write.
+func TestParseANSITextPersistsStateAcrossCalls(t *testing.T) {
+	var state ansiState
+
+	gotPlain, gotRanges := parseANSIText("\x1b[31m", &state)
+	if gotPlain != "" {
+		t.Fatalf("parseANSIText(%q) plain => %q, want empty", "\x1b[31m", gotPlain)
+	}
+	if len(gotRanges) != 0 {
+		t.Fatalf("parseANSIText(%q) ranges => %+v, want none", "\x1b[31m", gotRanges)
+	}
+
+	gotPlain, gotRanges = parseANSIText("error\n", &state)
+	if want := "error\n"; gotPlain != want {
+		t.Fatalf("parseANSIText(%q) plain => %q, want %q", "error\n", gotPlain, want)
+	}
+	if len(gotRanges) != 1 || gotRanges[0].low != 0 || gotRanges[0].high != len(gotPlain) {
+		t.Fatalf("parseANSIText(%q) ranges => %+v, want a single range covering the whole text", "error\n", gotRanges)
+	}
+
+	state.reset()
+	gotPlain, gotRanges = parseANSIText("plain\n", &state)
+	if want := "plain\n"; gotPlain != want {
+		t.Fatalf("parseANSIText(%q) plain => %q, want %q", "plain\n", gotPlain, want)
+	}
+	if len(gotRanges) != 0 {
+		t.Fatalf("parseANSIText(%q) ranges => %+v, want none after reset", "plain\n", gotRanges)
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		desc string
+		text string
+		want string
+	}{
+		{
+			desc: "no escape sequences",
+			text: "hello",
+			want: "hello",
+		},
+		{
+			desc: "colored text",
+			text: "\x1b[31mred\x1b[0m plain",
+			want: "red plain",
+		},
+		{
+			desc: "cursor movement is stripped too",
+			text: "a\x1b[2Ab",
+			want: "ab",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := StripANSI(tc.text); got != tc.want {
+				t.Errorf("StripANSI(%q) => %q, want %q", tc.text, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "sort"
+
+// findLines calculates the starting byte positions in text of every line
+// that will end up being displayed on a canvas of the given width,
+// accounting for newlines and, when enabled, word wrapping. It also returns
+// the width in cells of the widest line, which callers in horizontal
+// scrolling mode need in order to compute the maximum horizontal scroll
+// offset.
+func findLines(text string, width int, opts *options) ([]int, int) {
+	lines := []int{0}
+	curX := 0
+	maxWidth := 0
+	for i, r := range text {
+		if r == '\n' {
+			if curX > maxWidth {
+				maxWidth = curX
+			}
+			curX = 0
+			lines = append(lines, i+1)
+			continue
+		}
+		if wrapNeeded(r, curX, width, opts) {
+			curX = 0
+			lines = append(lines, i)
+		}
+		curX += runeWidth(r)
+	}
+	if curX > maxWidth {
+		maxWidth = curX
+	}
+	return lines, maxWidth
+}
+
+// lineBounds returns the byte range [low, high) of the visual line ln,
+// given the starting positions of all lines as calculated by findLines and
+// the length of the full text.
+func lineBounds(lines []int, ln int, textLen int) (low, high int) {
+	low = lines[ln]
+	if ln+1 < len(lines) {
+		high = lines[ln+1]
+	} else {
+		high = textLen
+	}
+	return low, high
+}
+
+// isParagraphEnd returns true if the visual line ln is the last one of its
+// paragraph, i.e. it is either terminated by a '\n' or is the last line of
+// the text. Lines that end because of word wrapping return false, as the
+// paragraph continues on the following visual line.
+func isParagraphEnd(text string, lines []int, ln int) bool {
+	if ln+1 >= len(lines) {
+		return true
+	}
+	nextLow := lines[ln+1]
+	return nextLow > 0 && text[nextLow-1] == '\n'
+}
+
+// lineForPos returns the index into lines (as calculated by findLines) of
+// the visual line containing the byte at position pos.
+func lineForPos(lines []int, pos int) int {
+	return sort.Search(len(lines), func(i int) bool { return lines[i] > pos }) - 1
+}
+
+// visualLinesDropped returns the number of visual lines, out of lines as
+// calculated by findLines over the text before some of its leading bytes
+// were evicted, that lie entirely within the first dropBytes bytes, i.e.
+// the number of visual lines a scroll position must be rebased by when
+// those bytes are discarded from the front of the text. dropBytes is
+// assumed to land exactly on a visual line boundary, which holds whenever
+// it was computed by ringTrim: it only ever drops complete, '\n'-terminated
+// lines, and the start of a line following a '\n' is always also a visual
+// line boundary, wrapping or not.
+func visualLinesDropped(lines []int, dropBytes int) int {
+	return sort.Search(len(lines), func(i int) bool { return lines[i] >= dropBytes })
+}
+
+// wrapNeeded returns true if a new line must be started before printing r in
+// order to keep it within the canvas, given the current X position of the
+// drawing cursor.
+func wrapNeeded(r rune, curX, width int, opts *options) bool {
+	if opts.wrapMode != wrapModeAtRunes {
+		return false
+	}
+	return curX >= width
+}
+
+// trimResult is the outcome of lineTrim.
+type trimResult struct {
+	// col is the canvas column the rune should be drawn at, valid only if
+	// trimmed is false.
+	col int
+	// trimmed indicates that the rune falls outside of the canvas, either
+	// because it is past the width of the canvas (trim and hscroll modes)
+	// or because it is scrolled out of view to the left (hscroll mode),
+	// and shouldn't be drawn.
+	trimmed bool
+}
+
+// lineTrim determines whether the rune about to be drawn at the logical
+// column lineX (i.e. the column within the line, ignoring any horizontal
+// scrolling) falls within the visible [xOffset, xOffset+width) range of the
+// canvas. In wrap mode xOffset is always zero and wrapping already keeps
+// lineX within range, so every rune is visible except trailing spaces that
+// alignment pushes past the right edge, as computeLineAlign leaves them out
+// of a line's width.
+func lineTrim(lineX, width, xOffset int) trimResult {
+	col := lineX - xOffset
+	if col < 0 || col >= width {
+		return trimResult{trimmed: true}
+	}
+	return trimResult{col: col}
+}
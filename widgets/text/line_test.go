@@ -0,0 +1,126 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "testing"
+
+func TestFindLinesHScrollTracksMaxWidth(t *testing.T) {
+	tests := []struct {
+		desc         string
+		text         string
+		width        int
+		wantLines    []int
+		wantMaxWidth int
+	}{
+		{
+			desc:         "single short line",
+			text:         "hello",
+			width:        10,
+			wantLines:    []int{0},
+			wantMaxWidth: 5,
+		},
+		{
+			desc:         "multiple lines of different lengths",
+			text:         "a\nbbb\ncc",
+			width:        10,
+			wantLines:    []int{0, 2, 6},
+			wantMaxWidth: 3,
+		},
+		{
+			desc:         "hscroll mode doesn't wrap regardless of width",
+			text:         "a very long single line with no newlines at all",
+			width:        5,
+			wantLines:    []int{0},
+			wantMaxWidth: 47,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := newOptions(HScroll())
+			lines, maxWidth := findLines(tc.text, tc.width, opts)
+			if diff := diffInts(lines, tc.wantLines); diff != "" {
+				t.Errorf("findLines(%q, %d) lines unexpected, %s", tc.text, tc.width, diff)
+			}
+			if maxWidth != tc.wantMaxWidth {
+				t.Errorf("findLines(%q, %d) maxWidth => %d, want %d", tc.text, tc.width, maxWidth, tc.wantMaxWidth)
+			}
+		})
+	}
+}
+
+func diffInts(got, want []int) string {
+	if len(got) != len(want) {
+		return "length mismatch"
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return "value mismatch"
+		}
+	}
+	return ""
+}
+
+func TestLineTrim(t *testing.T) {
+	tests := []struct {
+		desc        string
+		lineX       int
+		width       int
+		xOffset     int
+		wantTrimmed bool
+		wantCol     int
+	}{
+		{
+			desc:    "visible at the start of the canvas",
+			lineX:   0,
+			width:   10,
+			xOffset: 0,
+			wantCol: 0,
+		},
+		{
+			desc:        "past the width of the canvas",
+			lineX:       10,
+			width:       10,
+			xOffset:     0,
+			wantTrimmed: true,
+		},
+		{
+			desc:        "scrolled out of view to the left",
+			lineX:       2,
+			width:       10,
+			xOffset:     5,
+			wantTrimmed: true,
+		},
+		{
+			desc:    "visible with a positive offset",
+			lineX:   7,
+			width:   10,
+			xOffset: 5,
+			wantCol: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := lineTrim(tc.lineX, tc.width, tc.xOffset)
+			if got.trimmed != tc.wantTrimmed {
+				t.Errorf("lineTrim(%d, %d, %d).trimmed => %v, want %v", tc.lineX, tc.width, tc.xOffset, got.trimmed, tc.wantTrimmed)
+			}
+			if !got.trimmed && got.col != tc.wantCol {
+				t.Errorf("lineTrim(%d, %d, %d).col => %d, want %d", tc.lineX, tc.width, tc.xOffset, got.col, tc.wantCol)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/mouse"
+)
+
+// Option is used to provide options to New().
+type Option interface {
+	// set sets the provided option.
+	set(*options)
+}
+
+// option implements Option.
+type option func(*options)
+
+// set implements Option.set.
+func (o option) set(opts *options) {
+	o(opts)
+}
+
+// wrapMode determines how lines longer than the canvas width are handled.
+type wrapMode int
+
+const (
+	// wrapModeTrim trims lines that are longer than the width of the
+	// canvas. This is the default.
+	wrapModeTrim wrapMode = iota
+	// wrapModeAtRunes wraps lines that are longer than the width of the
+	// canvas onto the following line.
+	wrapModeAtRunes
+	// wrapModeHScroll never wraps, instead the lines can be scrolled
+	// horizontally to reveal the parts that don't fit on the canvas.
+	wrapModeHScroll
+)
+
+// alignMode determines how each visual line is positioned within the width
+// of the canvas.
+type alignMode int
+
+const (
+	// alignLeft aligns the line to the left edge of the canvas. This is the
+	// default.
+	alignLeft alignMode = iota
+	// alignRight aligns the line to the right edge of the canvas.
+	alignRight
+	// alignCenter centers the line within the width of the canvas.
+	alignCenter
+	// alignJustify stretches the line to span the full width of the
+	// canvas by distributing extra space across the gaps between words.
+	// The last line of a paragraph is never justified.
+	alignJustify
+)
+
+// options stores the provided options.
+type options struct {
+	wrapMode  wrapMode
+	alignMode alignMode
+
+	keyUp     keyboard.Key
+	keyDown   keyboard.Key
+	keyPgUp   keyboard.Key
+	keyPgDown keyboard.Key
+	keyLeft   keyboard.Key
+	keyRight  keyboard.Key
+	keyHome   keyboard.Key
+	keyEnd    keyboard.Key
+
+	keyNextMatch keyboard.Key
+	keyPrevMatch keyboard.Key
+
+	mouseUpButton   mouse.Button
+	mouseDownButton mouse.Button
+
+	disableScrolling bool
+
+	// followTail indicates that the widget should start pinned to the most
+	// recently written line and keep following it, see FollowTail.
+	followTail bool
+
+	// highlightCellOpts are the cell options overlaid on top of a search
+	// match's own cell options, see HighlightCellOpts.
+	highlightCellOpts []cell.Option
+
+	// maxLines and maxBytes bound the text buffer, see MaxLines and
+	// MaxBytes. Non-positive values mean unbounded.
+	maxLines int
+	maxBytes int
+
+	// parseANSI indicates that Write should interpret ANSI SGR escape
+	// sequences in its input, see ParseANSI.
+	parseANSI bool
+}
+
+// newOptions returns a new options instance with the default values and the
+// provided options applied on top of them.
+func newOptions(opts ...Option) *options {
+	o := &options{
+		wrapMode:  wrapModeTrim,
+		alignMode: alignLeft,
+
+		keyUp:     keyboard.ArrowUp,
+		keyDown:   keyboard.ArrowDown,
+		keyPgUp:   keyboard.PgUp,
+		keyPgDown: keyboard.PgDn,
+		keyLeft:   keyboard.ArrowLeft,
+		keyRight:  keyboard.ArrowRight,
+		keyHome:   keyboard.Home,
+		keyEnd:    keyboard.End,
+
+		keyNextMatch: keyboard.Key('n'),
+		keyPrevMatch: keyboard.Key('N'),
+
+		mouseUpButton:   mouse.ButtonWheelUp,
+		mouseDownButton: mouse.ButtonWheelDown,
+
+		highlightCellOpts: []cell.Option{cell.Reverse()},
+	}
+	for _, opt := range opts {
+		opt.set(o)
+	}
+	return o
+}
+
+// Trim instructs the widget to trim lines that are longer than the width of
+// the canvas. This is the default behavior.
+func Trim() Option {
+	return option(func(opts *options) {
+		opts.wrapMode = wrapModeTrim
+	})
+}
+
+// Wrap instructs the widget to wrap lines that are longer than the width of
+// the canvas onto the following line.
+func Wrap() Option {
+	return option(func(opts *options) {
+		opts.wrapMode = wrapModeAtRunes
+	})
+}
+
+// HScroll disables line wrapping, like Trim, but additionally allows the
+// lines that don't fit within the width of the canvas to be scrolled
+// horizontally with keyLeft, keyRight, keyHome and keyEnd. The horizontal
+// scroll position can be read, but not set, via Text.HScroll and
+// Text.MaxHScroll.
+func HScroll() Option {
+	return option(func(opts *options) {
+		opts.wrapMode = wrapModeHScroll
+	})
+}
+
+// DisableScrolling disables all the scrolling keyboard and mouse events,
+// i.e. the text will always be displayed from the first line.
+func DisableScrolling() Option {
+	return option(func(opts *options) {
+		opts.disableScrolling = true
+	})
+}
+
+// AlignLeft aligns every visual line to the left edge of the canvas. This
+// is the default.
+func AlignLeft() Option {
+	return option(func(opts *options) {
+		opts.alignMode = alignLeft
+	})
+}
+
+// AlignRight aligns every visual line to the right edge of the canvas.
+func AlignRight() Option {
+	return option(func(opts *options) {
+		opts.alignMode = alignRight
+	})
+}
+
+// AlignCenter centers every visual line within the width of the canvas.
+func AlignCenter() Option {
+	return option(func(opts *options) {
+		opts.alignMode = alignCenter
+	})
+}
+
+// Justify stretches every visual line to span the full width of the canvas
+// by distributing extra space across the gaps between words. The last
+// visual line of a paragraph (one ending in '\n', or the last line of the
+// text) is never justified, matching the usual behavior of justified text
+// in word processors.
+func Justify() Option {
+	return option(func(opts *options) {
+		opts.alignMode = alignJustify
+	})
+}
+
+// FollowTail makes the widget behave like `tail -f`: the view starts pinned
+// to the bottom of the text and stays there as new text arrives via Write.
+// Any vertical scrolling input from the keyboard or mouse disables following
+// until the last line is reached again, at which point following resumes;
+// horizontal scrolling leaves following as it is. See
+// also Text.SetFollow and Text.RollContent.
+func FollowTail() Option {
+	return option(func(opts *options) {
+		opts.followTail = true
+	})
+}
+
+// HighlightCellOpts sets the cell options used to highlight search matches
+// found via Text.Search. Applied on top of any cell options given to the
+// Write call that produced the matched text. Defaults to reverse video.
+func HighlightCellOpts(cOpts ...cell.Option) Option {
+	return option(func(opts *options) {
+		opts.highlightCellOpts = cOpts
+	})
+}
+
+// MaxLines bounds the text buffer to at most n lines. Once the bound is
+// exceeded, the oldest complete lines are discarded by the same Write,
+// turning the buffer into a ring suitable for streaming an indefinite
+// amount of text (e.g. a tail-style log viewer) without unbounded memory
+// growth. A non-positive value, the default, leaves the buffer unbounded.
+// See also Text.SetMaxLines and MaxBytes.
+func MaxLines(n int) Option {
+	return option(func(opts *options) {
+		opts.maxLines = n
+	})
+}
+
+// MaxBytes bounds the text buffer to at most n bytes. Like MaxLines, once
+// the bound is exceeded the oldest complete lines are discarded by the same
+// Write. A non-positive value, the default, leaves the buffer unbounded.
+// MaxLines and MaxBytes can be combined, in which case both bounds are
+// enforced.
+func MaxBytes(n int) Option {
+	return option(func(opts *options) {
+		opts.maxBytes = n
+	})
+}
+
+// ParseANSI makes Write interpret ANSI SGR escape sequences (e.g.
+// "\x1b[31m") found in its input, translating them into cell options
+// instead of rejecting the ESC character as an invalid control rune. The
+// sequences themselves are stripped before the text is stored; use
+// StripANSI to recover the plain text independently of the widget.
+// Non-SGR CSI sequences (e.g. cursor movement or erase) are recognized and
+// silently dropped, as they carry no meaning on a canvas of cells. A lone
+// ESC that isn't part of a recognized CSI sequence is left untouched and
+// will still be rejected by the usual control-character validation.
+//
+// Explicit cell options given via WriteCellOpts are ignored for a call in
+// which any of the text is styled by ANSI attributes, whether set in that
+// call or still in effect from an earlier one, since the two cannot be
+// combined unambiguously; they apply to the whole text of a call in which
+// none of it is.
+func ParseANSI() Option {
+	return option(func(opts *options) {
+		opts.parseANSI = true
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+// ringTrim determines how many complete lines must be dropped from the
+// front of the buffer in order to bring it within both maxLines (the number
+// of lines retained) and maxBytes (the number of bytes retained), either of
+// which is unbounded when not positive. Only lines terminated by '\n' are
+// ever dropped; a trailing, unterminated line counts towards maxLines and
+// maxBytes like any other but is never itself dropped, even if it alone
+// exceeds maxBytes.
+//
+// nlEnds holds the end position of every '\n'-terminated line currently in
+// the buffer (see Text.nlEnds for how it's maintained incrementally), as
+// absolute byte offsets that ignore any eviction done by prior calls; base
+// is the cumulative number of bytes already evicted by those prior calls,
+// used to translate nlEnds into offsets relative to the current start of the
+// buffer. bufLen is the current length of the buffer. Taking nlEnds and base
+// as already-known inputs, rather than rescanning the buffer for '\n' on
+// every call, is what keeps repeated calls against a long-running,
+// frequently-written buffer cheap.
+//
+// Returns the number of bytes and the number of complete lines to drop from
+// the front of the buffer; both are zero if nothing needs to be dropped.
+// dropBytes is relative to the current start of the buffer, matching what
+// Text.enforceRingBound passes to t.buff.Next.
+func ringTrim(nlEnds []int, base, bufLen, maxLines, maxBytes int) (dropBytes, dropLines int) {
+	if maxLines <= 0 && maxBytes <= 0 {
+		return 0, 0
+	}
+
+	totalLines := len(nlEnds)
+	if bufLen > 0 && (len(nlEnds) == 0 || nlEnds[len(nlEnds)-1]-base < bufLen) {
+		totalLines++ // Account for a trailing, unterminated line.
+	}
+
+	drop := 0
+	if maxLines > 0 && totalLines > maxLines {
+		drop = totalLines - maxLines
+		if drop > len(nlEnds) { // Never drop the unterminated trailing line.
+			drop = len(nlEnds)
+		}
+	}
+
+	if maxBytes > 0 {
+		remaining := bufLen
+		if drop > 0 {
+			remaining = bufLen - (nlEnds[drop-1] - base)
+		}
+		for remaining > maxBytes && drop < len(nlEnds) {
+			remaining = bufLen - (nlEnds[drop] - base)
+			drop++
+		}
+	}
+
+	if drop == 0 {
+		return 0, 0
+	}
+	return nlEnds[drop-1] - base, drop
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "testing"
+
+func TestRingTrim(t *testing.T) {
+	tests := []struct {
+		desc          string
+		content       string
+		maxLines      int
+		maxBytes      int
+		wantDropBytes int
+		wantDropLines int
+	}{
+		{
+			desc:    "unbounded when neither limit is set",
+			content: "a\nb\nc\n",
+		},
+		{
+			desc:     "within both bounds, nothing dropped",
+			content:  "a\nb\nc\n",
+			maxLines: 3,
+			maxBytes: 100,
+		},
+		{
+			desc:          "maxLines drops the oldest complete lines",
+			content:       "a\nb\nc\nd\n",
+			maxLines:      2,
+			wantDropBytes: 4, // "a\nb\n"
+			wantDropLines: 2,
+		},
+		{
+			desc:          "maxBytes drops the oldest complete lines",
+			content:       "aa\nbb\ncc\n",
+			maxBytes:      6,
+			wantDropBytes: 3, // "aa\n"
+			wantDropLines: 1,
+		},
+		{
+			desc:          "both limits combined, the stricter one wins",
+			content:       "a\nb\nc\nd\ne\n",
+			maxLines:      4,
+			maxBytes:      4,
+			wantDropBytes: 6, // maxLines alone would only drop "a\n"
+			wantDropLines: 3,
+		},
+		{
+			desc:          "an unterminated trailing line is never dropped",
+			content:       "a\nb\nunterminated",
+			maxLines:      1,
+			maxBytes:      1,
+			wantDropBytes: 4, // "a\nb\n", leaving just the trailing line.
+			wantDropLines: 2,
+		},
+		{
+			desc:     "an unterminated trailing line alone is never dropped",
+			content:  "unterminated",
+			maxLines: 1,
+			maxBytes: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			nlEnds := nlEndsOf(tc.content)
+			gotDropBytes, gotDropLines := ringTrim(nlEnds, 0, len(tc.content), tc.maxLines, tc.maxBytes)
+			if gotDropBytes != tc.wantDropBytes || gotDropLines != tc.wantDropLines {
+				t.Errorf("ringTrim(%v, 0, %d, %d, %d) => (%d, %d), want (%d, %d)",
+					nlEnds, len(tc.content), tc.maxLines, tc.maxBytes, gotDropBytes, gotDropLines, tc.wantDropBytes, tc.wantDropLines)
+			}
+		})
+	}
+}
+
+// nlEndsOf returns the nlEnds argument ringTrim expects for content taken in
+// isolation, i.e. as if no bytes had ever been dropped from in front of it.
+func nlEndsOf(content string) []int {
+	var ends []int
+	for i, r := range content {
+		if r == '\n' {
+			ends = append(ends, i+1)
+		}
+	}
+	return ends
+}
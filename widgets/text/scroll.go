@@ -0,0 +1,217 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+// scrollTracker tracks the scrolling position of the text widget, i.e.
+// which line of the text is displayed at the top of the canvas.
+//
+// When follow-tail mode is enabled, the tracker instead keeps the view
+// pinned to the bottom of the text, so that the most recently written line
+// is always visible, similar to `tail -f`.
+type scrollTracker struct {
+	// firstLn is the line displayed at the top of the canvas when the
+	// widget isn't following the tail of the text.
+	firstLn int
+
+	// following indicates whether the view is currently pinned to the
+	// bottom of the text.
+	following bool
+
+	// followCapable indicates that follow-tail behavior was opted into,
+	// either via the FollowTail option or a prior call to setFollow. It
+	// gates enableFollowIfAtBottom so that widgets which never asked for
+	// follow-tail mode don't silently start pinning to the bottom just
+	// because the user scrolled all the way down.
+	followCapable bool
+
+	// lastTotal and lastHeight are the arguments of the most recent call to
+	// firstLine, remembered so that upOnePage and downOnePage know the page
+	// size, and downOnePage and downOneLine can tell whether the bottom of
+	// the text was reached, without requiring the canvas dimensions to be
+	// passed to them too.
+	lastTotal  int
+	lastHeight int
+
+	// hOff is the current horizontal scroll offset, i.e. the number of
+	// cells the display is shifted to the left. Only meaningful in
+	// wrapModeHScroll.
+	hOff int
+
+	// lastMaxLineWidth and lastWidth are the arguments of the most recent
+	// call to hOffset, remembered so that leftOneCol, rightOneCol, homeCol
+	// and endCol don't need the canvas dimensions passed to them too.
+	lastMaxLineWidth int
+	lastWidth        int
+}
+
+// newScrollTracker returns a new scrollTracker configured according to the
+// provided options.
+func newScrollTracker(opts *options) *scrollTracker {
+	return &scrollTracker{
+		following:     opts.followTail,
+		followCapable: opts.followTail,
+	}
+}
+
+// maxFirstLine returns the highest line number that firstLine can return for
+// the given total number of lines and canvas height, i.e. the line number
+// that places the last line of the text at the bottom of the canvas.
+func maxFirstLine(total, height int) int {
+	if total <= height {
+		return 0
+	}
+	return total - height
+}
+
+// firstLine returns the line number that should be displayed at the top of
+// the canvas for the given total number of lines and canvas height.
+func (st *scrollTracker) firstLine(total, height int) int {
+	st.lastTotal = total
+	st.lastHeight = height
+
+	max := maxFirstLine(total, height)
+	if st.following {
+		st.firstLn = max
+		return st.firstLn
+	}
+
+	if st.firstLn > max {
+		st.firstLn = max
+	}
+	if st.firstLn < 0 {
+		st.firstLn = 0
+	}
+	return st.firstLn
+}
+
+// atBottom returns true if the last known firstLine is the bottom-most one
+// for the last known total and height.
+func (st *scrollTracker) atBottom() bool {
+	return st.firstLn >= maxFirstLine(st.lastTotal, st.lastHeight)
+}
+
+// disableFollow stops the view from following the tail of the text. Called
+// whenever the user scrolls explicitly.
+func (st *scrollTracker) disableFollow() {
+	st.following = false
+}
+
+// enableFollowIfAtBottom resumes following the tail of the text if the view
+// has reached the bottom again. This only has an effect on trackers that
+// have opted into follow-tail behavior, see followCapable.
+func (st *scrollTracker) enableFollowIfAtBottom() {
+	if st.followCapable && st.atBottom() {
+		st.following = true
+	}
+}
+
+// setFollow enables or disables follow-tail mode.
+func (st *scrollTracker) setFollow(follow bool) {
+	st.followCapable = true
+	st.following = follow
+}
+
+// upOneLine scrolls the view up by one line and disables following.
+func (st *scrollTracker) upOneLine() {
+	st.disableFollow()
+	if st.firstLn > 0 {
+		st.firstLn--
+	}
+}
+
+// downOneLine scrolls the view down by one line, disabling following first
+// and resuming it if the bottom of the text is reached as a result.
+func (st *scrollTracker) downOneLine() {
+	st.disableFollow()
+	if max := maxFirstLine(st.lastTotal, st.lastHeight); st.firstLn < max {
+		st.firstLn++
+	}
+	st.enableFollowIfAtBottom()
+}
+
+// upOnePage scrolls the view up by one page (the height of the canvas) and
+// disables following.
+func (st *scrollTracker) upOnePage() {
+	st.disableFollow()
+	st.firstLn -= st.lastHeight
+	if st.firstLn < 0 {
+		st.firstLn = 0
+	}
+}
+
+// downOnePage scrolls the view down by one page (the height of the canvas),
+// disabling following first and resuming it if the bottom of the text is
+// reached as a result.
+func (st *scrollTracker) downOnePage() {
+	st.disableFollow()
+	max := maxFirstLine(st.lastTotal, st.lastHeight)
+	st.firstLn += st.lastHeight
+	if st.firstLn > max {
+		st.firstLn = max
+	}
+	st.enableFollowIfAtBottom()
+}
+
+// maxHScroll returns the highest horizontal scroll offset for the given
+// width of the widest line in the text and the width of the canvas, i.e.
+// the offset that places the end of the widest line at the right edge of
+// the canvas.
+func maxHScroll(maxLineWidth, width int) int {
+	if maxLineWidth <= width {
+		return 0
+	}
+	return maxLineWidth - width
+}
+
+// hOffset returns the horizontal scroll offset that should be used to draw
+// a canvas of the given width, given the width of the widest line in the
+// text.
+func (st *scrollTracker) hOffset(maxLineWidth, width int) int {
+	st.lastMaxLineWidth = maxLineWidth
+	st.lastWidth = width
+
+	max := maxHScroll(maxLineWidth, width)
+	if st.hOff > max {
+		st.hOff = max
+	}
+	if st.hOff < 0 {
+		st.hOff = 0
+	}
+	return st.hOff
+}
+
+// leftOneCol scrolls the view one cell to the left.
+func (st *scrollTracker) leftOneCol() {
+	if st.hOff > 0 {
+		st.hOff--
+	}
+}
+
+// rightOneCol scrolls the view one cell to the right.
+func (st *scrollTracker) rightOneCol() {
+	if max := maxHScroll(st.lastMaxLineWidth, st.lastWidth); st.hOff < max {
+		st.hOff++
+	}
+}
+
+// homeCol scrolls the view all the way to the left.
+func (st *scrollTracker) homeCol() {
+	st.hOff = 0
+}
+
+// endCol scrolls the view all the way to the right.
+func (st *scrollTracker) endCol() {
+	st.hOff = maxHScroll(st.lastMaxLineWidth, st.lastWidth)
+}
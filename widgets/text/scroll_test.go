@@ -0,0 +1,206 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "testing"
+
+// TestScrollTrackerFollowTransitions verifies that follow-tail mode is
+// disabled by user scrolling and re-enabled once the bottom is reached
+// again.
+func TestScrollTrackerFollowTransitions(t *testing.T) {
+	const (
+		total  = 10
+		height = 3
+	)
+
+	st := newScrollTracker(newOptions(FollowTail()))
+
+	if got := st.firstLine(total, height); got != maxFirstLine(total, height) {
+		t.Fatalf("firstLine => %d, want %d (follow should start pinned to the bottom)", got, maxFirstLine(total, height))
+	}
+
+	st.upOneLine()
+	if st.following {
+		t.Fatalf("following => true after upOneLine, want false")
+	}
+	if got, want := st.firstLine(total, height), maxFirstLine(total, height)-1; got != want {
+		t.Errorf("firstLine => %d, want %d", got, want)
+	}
+
+	// Scrolling back down to the bottom re-enables following.
+	st.downOneLine()
+	if !st.following {
+		t.Fatalf("following => false after downOneLine reached the bottom, want true")
+	}
+	if got := st.firstLine(total, height); got != maxFirstLine(total, height) {
+		t.Errorf("firstLine => %d, want %d", got, maxFirstLine(total, height))
+	}
+}
+
+// TestScrollTrackerNotFollowingByDefault verifies that follow-tail mode is
+// opt-in: without the FollowTail option, reaching the bottom of the text
+// does not start pinning the view to new content.
+func TestScrollTrackerNotFollowingByDefault(t *testing.T) {
+	const (
+		total  = 9
+		height = 3
+	)
+
+	st := newScrollTracker(newOptions())
+	if st.following {
+		t.Fatalf("following => true for a tracker created without FollowTail, want false")
+	}
+	st.firstLine(total, height) // Establish lastTotal/lastHeight, as Draw would.
+
+	st.downOnePage()
+	st.downOnePage()
+	if got := st.firstLine(total, height); got != maxFirstLine(total, height) {
+		t.Fatalf("firstLine => %d, want %d", got, maxFirstLine(total, height))
+	}
+	if st.following {
+		t.Errorf("following => true after reaching the bottom without FollowTail, want false")
+	}
+}
+
+// TestScrollTrackerSetFollow verifies that SetFollow can toggle follow-tail
+// mode at runtime regardless of the option the tracker was created with.
+func TestScrollTrackerSetFollow(t *testing.T) {
+	st := newScrollTracker(newOptions())
+	st.firstLine(10, 3)
+
+	st.setFollow(true)
+	if got := st.firstLine(10, 3); got != maxFirstLine(10, 3) {
+		t.Fatalf("firstLine => %d, want %d after SetFollow(true)", got, maxFirstLine(10, 3))
+	}
+
+	st.setFollow(false)
+	st.upOneLine()
+	if st.following {
+		t.Errorf("following => true after SetFollow(false) and upOneLine, want false")
+	}
+}
+
+// TestScrollTrackerUpOnePageDisablesFollow verifies that paging up also
+// disables following, not just single-line scrolling.
+func TestScrollTrackerUpOnePageDisablesFollow(t *testing.T) {
+	st := newScrollTracker(newOptions(FollowTail()))
+	st.firstLine(20, 5)
+
+	st.upOnePage()
+	if st.following {
+		t.Errorf("following => true after upOnePage, want false")
+	}
+
+	st.downOnePage()
+	st.downOnePage()
+	st.downOnePage()
+	if !st.following {
+		t.Errorf("following => false after paging back down to the bottom, want true")
+	}
+}
+
+// TestMaxHScroll verifies the maxHScroll helper.
+func TestMaxHScroll(t *testing.T) {
+	tests := []struct {
+		desc         string
+		maxLineWidth int
+		width        int
+		want         int
+	}{
+		{
+			desc:         "widest line fits within the canvas",
+			maxLineWidth: 5,
+			width:        10,
+			want:         0,
+		},
+		{
+			desc:         "widest line exactly matches the canvas width",
+			maxLineWidth: 10,
+			width:        10,
+			want:         0,
+		},
+		{
+			desc:         "widest line is wider than the canvas",
+			maxLineWidth: 15,
+			width:        10,
+			want:         5,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := maxHScroll(tc.maxLineWidth, tc.width); got != tc.want {
+				t.Errorf("maxHScroll(%d, %d) => %d, want %d", tc.maxLineWidth, tc.width, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestScrollTrackerHOffsetClamping verifies that hOffset clamps a stale
+// horizontal offset into range when the canvas or the widest line changes.
+func TestScrollTrackerHOffsetClamping(t *testing.T) {
+	st := newScrollTracker(newOptions())
+
+	if got, want := st.hOffset(20, 10), 0; got != want {
+		t.Fatalf("hOffset => %d, want %d", got, want)
+	}
+
+	st.hOff = 100 // Simulates a stale offset from a wider canvas or longer line.
+	if got, want := st.hOffset(20, 10), maxHScroll(20, 10); got != want {
+		t.Errorf("hOffset => %d, want %d (clamped to the new max)", got, want)
+	}
+
+	st.hOff = -5
+	if got, want := st.hOffset(20, 10), 0; got != want {
+		t.Errorf("hOffset => %d, want %d (clamped to zero)", got, want)
+	}
+}
+
+// TestScrollTrackerHorizontalScrolling exercises leftOneCol, rightOneCol,
+// homeCol and endCol, mirroring the vertical scrolling tests above.
+func TestScrollTrackerHorizontalScrolling(t *testing.T) {
+	st := newScrollTracker(newOptions())
+	st.hOffset(20, 10) // Establish lastMaxLineWidth/lastWidth, as Draw would.
+
+	st.rightOneCol()
+	if got, want := st.hOff, 1; got != want {
+		t.Fatalf("hOff => %d, want %d after rightOneCol", got, want)
+	}
+
+	st.leftOneCol()
+	if got, want := st.hOff, 0; got != want {
+		t.Errorf("hOff => %d, want %d after leftOneCol", got, want)
+	}
+
+	st.leftOneCol() // Already at the left edge, must not go negative.
+	if got, want := st.hOff, 0; got != want {
+		t.Errorf("hOff => %d, want %d after leftOneCol at the left edge", got, want)
+	}
+
+	st.endCol()
+	if got, want := st.hOff, maxHScroll(20, 10); got != want {
+		t.Fatalf("hOff => %d, want %d after endCol", got, want)
+	}
+
+	st.rightOneCol() // Already at the right edge, must not exceed the max.
+	if got, want := st.hOff, maxHScroll(20, 10); got != want {
+		t.Errorf("hOff => %d, want %d after rightOneCol at the right edge", got, want)
+	}
+
+	st.homeCol()
+	if got, want := st.hOff, 0; got != want {
+		t.Errorf("hOff => %d, want %d after homeCol", got, want)
+	}
+}
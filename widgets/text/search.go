@@ -0,0 +1,157 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SearchOption is used to provide options to Search().
+type SearchOption interface {
+	// set sets the provided option.
+	set(*searchOptions)
+}
+
+// searchOption implements SearchOption.
+type searchOption func(*searchOptions)
+
+// set implements SearchOption.set.
+func (so searchOption) set(opts *searchOptions) {
+	so(opts)
+}
+
+// searchOptions stores the options provided to Search().
+type searchOptions struct {
+	caseInsensitive bool
+	literal         bool
+}
+
+// newSearchOptions returns a new searchOptions instance with the provided
+// options applied on top of the zero value.
+func newSearchOptions(opts ...SearchOption) *searchOptions {
+	so := &searchOptions{}
+	for _, opt := range opts {
+		opt.set(so)
+	}
+	return so
+}
+
+// SearchCaseInsensitive makes Search match regardless of case. The default
+// is a case-sensitive search.
+func SearchCaseInsensitive() SearchOption {
+	return searchOption(func(opts *searchOptions) {
+		opts.caseInsensitive = true
+	})
+}
+
+// SearchLiteral instructs Search to treat the pattern as a literal string
+// rather than a regular expression. The default interprets the pattern as a
+// regular expression.
+func SearchLiteral() SearchOption {
+	return searchOption(func(opts *searchOptions) {
+		opts.literal = true
+	})
+}
+
+// matchRange is the byte range [low, high) of one match in the text buffer.
+type matchRange struct {
+	low  int
+	high int
+}
+
+// searchState tracks an active search, i.e. the compiled pattern and the
+// byte ranges of every match found in the current content of the buffer.
+type searchState struct {
+	// re is the compiled search pattern.
+	re *regexp.Regexp
+	// matches are the byte ranges of every match, sorted by low in
+	// ascending order (the order regexp.FindAllStringIndex returns them).
+	matches []matchRange
+	// current is the index into matches of the currently selected match,
+	// or -1 if there are no matches.
+	current int
+}
+
+// newSearchState compiles pattern according to the provided options and
+// returns a searchState with no matches yet found. Call findMatches to
+// populate it against the current buffer content.
+func newSearchState(pattern string, opts ...SearchOption) (*searchState, error) {
+	so := newSearchOptions(opts...)
+
+	expr := pattern
+	if so.literal {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if so.caseInsensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile search pattern %q: %v", pattern, err)
+	}
+	return &searchState{re: re, current: -1}, nil
+}
+
+// findMatches re-runs the search pattern against text and updates matches
+// and current accordingly. Called whenever the pattern changes or the
+// buffer is mutated.
+func (ss *searchState) findMatches(text string) {
+	var matches []matchRange
+	for _, idx := range ss.re.FindAllStringIndex(text, -1) {
+		matches = append(matches, matchRange{low: idx[0], high: idx[1]})
+	}
+	ss.matches = matches
+
+	switch {
+	case len(matches) == 0:
+		ss.current = -1
+	case ss.current < 0:
+		ss.current = 0
+	case ss.current >= len(matches):
+		ss.current = len(matches) - 1
+	}
+}
+
+// next advances to the next match, wrapping around to the first one.
+func (ss *searchState) next() {
+	if len(ss.matches) == 0 {
+		return
+	}
+	ss.current = (ss.current + 1) % len(ss.matches)
+}
+
+// prev moves to the previous match, wrapping around to the last one.
+func (ss *searchState) prev() {
+	if len(ss.matches) == 0 {
+		return
+	}
+	ss.current = (ss.current - 1 + len(ss.matches)) % len(ss.matches)
+}
+
+// highlightIdx returns the index into matches that byte position pos falls
+// within, and whether pos falls within any match at all. idx must be
+// initialized to 0 by the caller and reused across increasing values of pos
+// within the same pass, as it only ever advances forward.
+func (ss *searchState) highlightIdx(pos int, idx int) (int, bool) {
+	for idx < len(ss.matches) && pos >= ss.matches[idx].high {
+		idx++
+	}
+	if idx < len(ss.matches) && pos >= ss.matches[idx].low && pos < ss.matches[idx].high {
+		return idx, true
+	}
+	return idx, false
+}
@@ -0,0 +1,193 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "testing"
+
+func TestNewSearchStateInvalidPattern(t *testing.T) {
+	if _, err := newSearchState("("); err == nil {
+		t.Errorf("newSearchState(%q) => nil error, want an error for an invalid regular expression", "(")
+	}
+}
+
+func TestSearchStateFindMatches(t *testing.T) {
+	tests := []struct {
+		desc        string
+		pattern     string
+		opts        []SearchOption
+		text        string
+		wantMatches []matchRange
+		wantCurrent int
+	}{
+		{
+			desc:        "no matches",
+			pattern:     "zzz",
+			text:        "foo bar baz",
+			wantMatches: nil,
+			wantCurrent: -1,
+		},
+		{
+			desc:    "regexp pattern matches every occurrence",
+			pattern: "ba.",
+			text:    "foo bar baz",
+			wantMatches: []matchRange{
+				{low: 4, high: 7},
+				{low: 8, high: 11},
+			},
+			wantCurrent: 0,
+		},
+		{
+			desc:    "literal pattern is not interpreted as a regexp",
+			pattern: "ba.",
+			opts:    []SearchOption{SearchLiteral()},
+			text:    "foo ba. bar",
+			wantMatches: []matchRange{
+				{low: 4, high: 7},
+			},
+			wantCurrent: 0,
+		},
+		{
+			desc:    "case insensitive search",
+			pattern: "FOO",
+			opts:    []SearchOption{SearchCaseInsensitive()},
+			text:    "foo FOO Foo",
+			wantMatches: []matchRange{
+				{low: 0, high: 3},
+				{low: 4, high: 7},
+				{low: 8, high: 11},
+			},
+			wantCurrent: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			ss, err := newSearchState(tc.pattern, tc.opts...)
+			if err != nil {
+				t.Fatalf("newSearchState => unexpected error: %v", err)
+			}
+			ss.findMatches(tc.text)
+
+			if len(ss.matches) != len(tc.wantMatches) {
+				t.Fatalf("findMatches => matches %v, want %v", ss.matches, tc.wantMatches)
+			}
+			for i, want := range tc.wantMatches {
+				if got := ss.matches[i]; got != want {
+					t.Errorf("findMatches => matches[%d] = %v, want %v", i, got, want)
+				}
+			}
+			if ss.current != tc.wantCurrent {
+				t.Errorf("findMatches => current %d, want %d", ss.current, tc.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestSearchStateNextPrev(t *testing.T) {
+	ss, err := newSearchState("a")
+	if err != nil {
+		t.Fatalf("newSearchState => unexpected error: %v", err)
+	}
+	ss.findMatches("a a a") // Three matches, current starts at 0.
+
+	ss.next()
+	if ss.current != 1 {
+		t.Fatalf("next => current %d, want 1", ss.current)
+	}
+	ss.next()
+	ss.next() // Wraps back around to the first match.
+	if ss.current != 0 {
+		t.Fatalf("next => current %d, want 0", ss.current)
+	}
+
+	ss.prev() // Wraps around to the last match.
+	if ss.current != 2 {
+		t.Fatalf("prev => current %d, want 2", ss.current)
+	}
+	ss.prev()
+	if ss.current != 1 {
+		t.Errorf("prev => current %d, want 1", ss.current)
+	}
+}
+
+func TestSearchStateNextPrevNoMatches(t *testing.T) {
+	ss, err := newSearchState("zzz")
+	if err != nil {
+		t.Fatalf("newSearchState => unexpected error: %v", err)
+	}
+	ss.findMatches("foo bar")
+
+	ss.next()
+	ss.prev()
+	if ss.current != -1 {
+		t.Errorf("current => %d after next/prev with no matches, want -1", ss.current)
+	}
+}
+
+func TestSearchStateFindMatchesPreservesCurrentAcrossMutation(t *testing.T) {
+	ss, err := newSearchState("a")
+	if err != nil {
+		t.Fatalf("newSearchState => unexpected error: %v", err)
+	}
+	ss.findMatches("a a")
+	ss.next()
+	if ss.current != 1 {
+		t.Fatalf("current => %d, want 1", ss.current)
+	}
+
+	// More text is written, adding another match. The second match (now at
+	// index 1) should remain selected.
+	ss.findMatches("a a a")
+	if ss.current != 1 {
+		t.Errorf("current => %d after findMatches grew the match set, want 1", ss.current)
+	}
+
+	// The buffer is reset to content with fewer matches than the previously
+	// selected index; current must clamp to the last available match.
+	ss.findMatches("a")
+	if ss.current != 0 {
+		t.Errorf("current => %d after findMatches shrank the match set, want 0", ss.current)
+	}
+}
+
+func TestSearchStateHighlightIdx(t *testing.T) {
+	ss, err := newSearchState("ba.")
+	if err != nil {
+		t.Fatalf("newSearchState => unexpected error: %v", err)
+	}
+	ss.findMatches("foo bar baz") // Matches at [4,7) and [8,11).
+
+	tests := []struct {
+		pos           int
+		wantHighlight bool
+	}{
+		{pos: 0, wantHighlight: false},
+		{pos: 3, wantHighlight: false},
+		{pos: 4, wantHighlight: true},
+		{pos: 6, wantHighlight: true},
+		{pos: 7, wantHighlight: false},
+		{pos: 8, wantHighlight: true},
+		{pos: 10, wantHighlight: true},
+	}
+
+	idx := 0
+	for _, tc := range tests {
+		var highlighted bool
+		idx, highlighted = ss.highlightIdx(tc.pos, idx)
+		if highlighted != tc.wantHighlight {
+			t.Errorf("highlightIdx(%d) => %v, want %v", tc.pos, highlighted, tc.wantHighlight)
+		}
+	}
+}
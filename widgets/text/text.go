@@ -24,6 +24,7 @@ import (
 	"unicode"
 
 	"github.com/mum4k/termdash/canvas"
+	"github.com/mum4k/termdash/cell"
 	"github.com/mum4k/termdash/terminalapi"
 	"github.com/mum4k/termdash/widgetapi"
 )
@@ -56,6 +57,33 @@ type Text struct {
 	// lines stores the starting locations in bytes of all the lines in the
 	// buffer. I.e. positions of newline characters and of any calculated line wraps.
 	lines []int
+	// maxLineWidth is the width in cells of the widest line in lines, used
+	// to compute the maximum horizontal scroll offset in wrapModeHScroll.
+	maxLineWidth int
+
+	// search holds the state of the active search, or nil if Search hasn't
+	// been called (or ClearSearch has).
+	search *searchState
+
+	// nlEnds holds the end position of every '\n'-terminated line currently
+	// in the buffer, as absolute byte offsets that keep growing for the
+	// lifetime of the widget rather than being rebased to the current start
+	// of the buffer on every eviction; see ringTrim and totalDropped. Updated
+	// incrementally by Write as text is appended, so that enforceRingBound
+	// never needs to rescan the retained buffer for '\n' to find its drop
+	// point. Rebasing givenWOpts on an eviction still visits every retained
+	// range.
+	nlEnds []int
+	// totalDropped is the cumulative number of bytes ever evicted from the
+	// front of the buffer by enforceRingBound, used to translate nlEnds into
+	// offsets relative to the current start of buff.
+	totalDropped int
+
+	// ansiState carries the SGR attributes in effect across successive Write
+	// calls, when the ParseANSI option is in use, so that a style set in one
+	// call and never reset still applies to text written in the next. Reset
+	// only by Reset, mirroring a real terminal.
+	ansiState ansiState
 
 	// mu protects the Text widget.
 	mu sync.Mutex
@@ -85,6 +113,10 @@ func (t *Text) Reset() {
 	t.lastWidth = 0
 	t.newText = true
 	t.lines = nil
+	t.search = nil
+	t.nlEnds = nil
+	t.totalDropped = 0
+	t.ansiState = ansiState{}
 }
 
 // Write writes text for the widget to display. Multiple calls append
@@ -93,23 +125,261 @@ func (t *Text) Reset() {
 //   ' ', '\n'
 // Any newline ('\n') characters are interpreted as newlines when displaying
 // the text.
+//
+// If the ParseANSI option was given to New, text may additionally contain
+// ANSI SGR escape sequences, which are translated into cell options and
+// stripped before the text is stored; see ParseANSI for the details and
+// its interaction with WriteCellOpts.
 func (t *Text) Write(text string, wOpts ...WriteOption) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	var styleRanges []styleRange
+	if t.opts.parseANSI {
+		text, styleRanges = parseANSIText(text, &t.ansiState)
+	}
+
 	if err := validText(text); err != nil {
 		return err
 	}
 
 	pos := t.buff.Len()
-	t.givenWOpts[pos] = newOptsRange(pos, pos+len(text), newWriteOptions(wOpts...))
+	if len(styleRanges) > 0 {
+		for _, sr := range styleRanges {
+			t.givenWOpts[pos+sr.low] = newOptsRange(pos+sr.low, pos+sr.high, writeOptions{cellOpts: sr.opts})
+		}
+	} else {
+		t.givenWOpts[pos] = newOptsRange(pos, pos+len(text), newWriteOptions(wOpts...))
+	}
 	if _, err := t.buff.WriteString(text); err != nil {
 		return err
 	}
+	for i, r := range text {
+		if r == '\n' {
+			t.nlEnds = append(t.nlEnds, t.totalDropped+pos+i+1)
+		}
+	}
 	t.newText = true
+	t.enforceRingBound()
+	if t.search != nil {
+		t.search.findMatches(t.buff.String())
+	}
 	return nil
 }
 
+// SetMaxLines sets the maximum number of lines retained in the buffer at
+// runtime, immediately enforcing the bound against the current content. A
+// non-positive value disables the bound. See also the MaxLines option.
+func (t *Text) SetMaxLines(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.opts.maxLines = n
+	t.enforceRingBound()
+	if t.search != nil {
+		t.search.findMatches(t.buff.String())
+	}
+}
+
+// enforceRingBound drops the oldest complete lines from the buffer, if
+// needed, to bring it within opts.maxLines and opts.maxBytes, rebasing
+// givenWOpts and the scroll position accordingly. Called with t.mu already
+// held.
+func (t *Text) enforceRingBound() {
+	dropBytes, dropLines := ringTrim(t.nlEnds, t.totalDropped, t.buff.Len(), t.opts.maxLines, t.opts.maxBytes)
+	if dropBytes == 0 {
+		return
+	}
+
+	// t.lines is indexed in visual (wrap-aware) lines, while dropLines counts
+	// raw '\n'-terminated lines; convert using the visual line table from
+	// before the eviction, as a raw line that wrapped onto more than one
+	// visual line would otherwise under-correct the scroll position.
+	dropVisualLines := dropLines
+	if len(t.lines) > 0 {
+		dropVisualLines = visualLinesDropped(t.lines, dropBytes)
+	}
+
+	t.buff.Next(dropBytes) // Discards the dropped bytes from the front.
+	t.givenWOpts = t.givenWOpts.rebase(dropBytes)
+	t.nlEnds = t.nlEnds[dropLines:]
+	t.totalDropped += dropBytes
+	t.newText = true
+
+	// Rebase the visual line table too, so that a further eviction before the
+	// next Draw still converts against lines matching the current buffer.
+	if len(t.lines) > 0 {
+		rebased := make([]int, 0, len(t.lines)-dropVisualLines)
+		for _, l := range t.lines[dropVisualLines:] {
+			rebased = append(rebased, l-dropBytes)
+		}
+		t.lines = rebased
+	}
+
+	// Preserve the scroll position relative to the content that remains.
+	// When following the tail, firstLine always re-pins to the bottom on
+	// the next Draw regardless of firstLn, so no special-casing is needed
+	// here.
+	t.scroll.firstLn -= dropVisualLines
+	if t.scroll.firstLn < 0 {
+		t.scroll.firstLn = 0
+	}
+}
+
+// Search searches the current text content for pattern and highlights every
+// match found. The first match becomes the current one, see CurrentMatch.
+// Use NextMatch and PrevMatch to navigate between matches. Calling Search
+// again replaces the previous search, and any text written afterwards via
+// Write is automatically re-searched.
+func (t *Text) Search(pattern string, opts ...SearchOption) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ss, err := newSearchState(pattern, opts...)
+	if err != nil {
+		return err
+	}
+	ss.findMatches(t.buff.String())
+	t.search = ss
+	return nil
+}
+
+// ClearSearch clears the active search, if any, and its highlighting.
+func (t *Text) ClearSearch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.search = nil
+}
+
+// NextMatch selects the next search match, wrapping around to the first one.
+// Has no effect if there is no active search or it has no matches. See also
+// the default keyNextMatch key.
+func (t *Text) NextMatch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nextMatch()
+}
+
+// nextMatch is the implementation of NextMatch, called with t.mu already held.
+func (t *Text) nextMatch() {
+	if t.search != nil {
+		t.search.next()
+	}
+}
+
+// PrevMatch selects the previous search match, wrapping around to the last
+// one. Has no effect if there is no active search or it has no matches. See
+// also the default keyPrevMatch key.
+func (t *Text) PrevMatch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prevMatch()
+}
+
+// prevMatch is the implementation of PrevMatch, called with t.mu already held.
+func (t *Text) prevMatch() {
+	if t.search != nil {
+		t.search.prev()
+	}
+}
+
+// MatchCount returns the number of matches found by the most recent Search,
+// or zero if there is no active search. Intended for status-line integration.
+func (t *Text) MatchCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.search == nil {
+		return 0
+	}
+	return len(t.search.matches)
+}
+
+// CurrentMatch returns the one-based index of the currently selected search
+// match, or zero if there is no active search or it has no matches. Intended
+// for status-line integration, e.g. displaying "match 2 of 5".
+func (t *Text) CurrentMatch() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.search == nil || t.search.current < 0 {
+		return 0
+	}
+	return t.search.current + 1
+}
+
+// RollContent scrolls the view to the bottom of the text as it was laid out
+// by the most recent call to Draw, without enabling persistent follow-tail
+// mode; lines written since that Draw are not taken into account. Use SetFollow or
+// the FollowTail option to keep the view pinned to the bottom as new text
+// arrives.
+func (t *Text) RollContent() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.scroll.firstLn = maxFirstLine(len(t.lines), t.scroll.lastHeight)
+}
+
+// SetFollow enables or disables follow-tail mode at runtime. While enabled,
+// Draw keeps the view pinned to the bottom of the text so that new text
+// written via Write is always visible. Any vertical scrolling input from the
+// keyboard or mouse disables following until the last line is reached
+// again. See also FollowTail.
+func (t *Text) SetFollow(follow bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.scroll.setFollow(follow)
+}
+
+// HScroll returns the current horizontal scroll offset, i.e. the number of
+// cells the display is currently shifted to the left. Only meaningful when
+// the widget was created with the HScroll option.
+func (t *Text) HScroll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.scroll.hOff
+}
+
+// MaxHScroll returns the highest horizontal scroll offset for the text and
+// canvas width used in the most recent call to Draw. Only meaningful when
+// the widget was created with the HScroll option, as the canvas width is
+// only recorded in that mode.
+func (t *Text) MaxHScroll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return maxHScroll(t.maxLineWidth, t.scroll.lastWidth)
+}
+
+// Scroll returns the line number currently displayed at the top of the
+// canvas.
+func (t *Text) Scroll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.scroll.firstLn
+}
+
+// MaxScroll returns the highest line number Scroll can return for the text
+// and canvas height used in the most recent call to Draw.
+func (t *Text) MaxScroll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return maxFirstLine(t.scroll.lastTotal, t.scroll.lastHeight)
+}
+
+// BottomScroll returns the scroll value that places the last line of the
+// text at the bottom of the canvas, equivalent to MaxScroll.
+func (t *Text) BottomScroll() int {
+	return t.MaxScroll()
+}
+
 // minLinesForMarkers are the minimum amount of lines required on the canvas in
 // order to draw the scroll markers ('⇧' and '⇩').
 const minLinesForMarkers = 3
@@ -151,13 +421,89 @@ func (t *Text) drawScrollDown(cvs *canvas.Canvas, cur image.Point, fromLine int)
 	return false, nil
 }
 
+// minColsForHMarkers are the minimum amount of columns required on the
+// canvas in order to draw the horizontal scroll markers ('⇦' and '⇨').
+const minColsForHMarkers = 3
+
+// drawScrollLeft draws the scroll left marker at the start of a row if the
+// view is scrolled horizontally at all, i.e. xOffset is positive, whether or
+// not that particular row has text hidden to the "left". Returns true if the
+// marker was drawn.
+func (t *Text) drawScrollLeft(cvs *canvas.Canvas, cur image.Point, xOffset int) (bool, error) {
+	width := cvs.Area().Dx()
+	if cur.X == 0 && width >= minColsForHMarkers && xOffset > 0 {
+		cells, err := cvs.SetCell(cur, '⇦')
+		if err != nil {
+			return false, err
+		}
+		if cells != 1 {
+			panic(fmt.Errorf("invalid scroll left marker, it occupies %d cells, the implementation only supports scroll markers that occupy exactly one cell", cells))
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawScrollRight draws the scroll right marker at the end of a row if the
+// view can be scrolled further to the "right", i.e. the widest line of the
+// text extends past the right edge, whether or not that particular row does.
+// Returns true if the marker was drawn.
+func (t *Text) drawScrollRight(cvs *canvas.Canvas, cur image.Point, xOffset int) (bool, error) {
+	width := cvs.Area().Dx()
+	if cur.X == width-1 && width >= minColsForHMarkers && xOffset < maxHScroll(t.maxLineWidth, width) {
+		cells, err := cvs.SetCell(cur, '⇨')
+		if err != nil {
+			return false, err
+		}
+		if cells != 1 {
+			panic(fmt.Errorf("invalid scroll right marker, it occupies %d cells, the implementation only supports scroll markers that occupy exactly one cell", cells))
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// scrollToCurrentMatch adjusts the vertical scroll position, if needed, so
+// that the line containing the currently selected search match is visible on
+// a canvas of the given height. A no-op if there is no active search or it
+// has no matches. While the tracker is following the tail, firstLine re-pins
+// the view to the bottom afterwards, so the match is only brought into view
+// when following is off.
+func (t *Text) scrollToCurrentMatch(height int) {
+	if t.search == nil || t.search.current < 0 {
+		return
+	}
+	matchLn := lineForPos(t.lines, t.search.matches[t.search.current].low)
+	switch {
+	case matchLn < t.scroll.firstLn:
+		t.scroll.firstLn = matchLn
+	case matchLn >= t.scroll.firstLn+height:
+		t.scroll.firstLn = matchLn - height + 1
+	}
+}
+
 // draw draws the text context on the canvas starting at the specified line.
 func (t *Text) draw(text string, cvs *canvas.Canvas) error {
 	var cur image.Point // Tracks the current drawing position on the canvas.
+	rawCol := 0         // Tracks the logical column within the line, unaffected by alignment or horizontal scrolling.
+	curExtra := 0       // Cumulative extra spaces inserted so far on the current line by Justify.
 	height := cvs.Area().Dy()
+	width := cvs.Area().Dx()
+	t.scrollToCurrentMatch(height)
 	fromLine := t.scroll.firstLine(len(t.lines), height)
+
+	var xOffset int
+	if t.opts.wrapMode == wrapModeHScroll {
+		xOffset = t.scroll.hOffset(t.maxLineWidth, width)
+	}
+
+	ln := fromLine
+	low, high := lineBounds(t.lines, ln, len(text))
+	curAlign := computeLineAlign(text, low, high, width, t.opts, isParagraphEnd(text, t.lines, ln))
+
 	optRange := t.givenWOpts.forPosition(0) // Text options for the current byte.
-	startPos := t.lines[fromLine]
+	searchIdx := 0                          // Tracks progress through t.search.matches.
+	startPos := low
 	for i, r := range text {
 		if i < startPos {
 			continue
@@ -170,13 +516,21 @@ func (t *Text) draw(text string, cvs *canvas.Canvas) error {
 		}
 		if scrlUp {
 			cur = image.Point{0, cur.Y + 1} // Move to the next line.
-			startPos = t.lines[fromLine+1]  // Skip one line of text, the marker replaced it.
+			rawCol, curExtra = 0, 0
+			ln++ // Skip one line of text, the marker replaced it.
+			low, high = lineBounds(t.lines, ln, len(text))
+			curAlign = computeLineAlign(text, low, high, width, t.opts, isParagraphEnd(text, t.lines, ln))
+			startPos = low
 			continue
 		}
 
 		// Line wrapping.
-		if r == '\n' || wrapNeeded(r, cur.X, cvs.Area().Dx(), t.opts) {
+		if r == '\n' || wrapNeeded(r, rawCol, width, t.opts) {
 			cur = image.Point{0, cur.Y + 1} // Move to the next line.
+			rawCol, curExtra = 0, 0
+			ln++
+			low, high = lineBounds(t.lines, ln, len(text))
+			curAlign = computeLineAlign(text, low, high, width, t.opts, isParagraphEnd(text, t.lines, ln))
 		}
 
 		// Scroll down marker.
@@ -188,26 +542,52 @@ func (t *Text) draw(text string, cvs *canvas.Canvas) error {
 			break // Trim all lines falling after the canvas.
 		}
 
-		tr, err := lineTrim(cvs, cur, r, t.opts)
-		if err != nil {
-			return err
+		if r == '\n' {
+			continue // Don't print the newline runes, just interpret them above.
 		}
-		cur = tr.curPoint
-		if tr.trimmed {
-			continue // Skip over any characters trimmed on the current line.
+
+		if n, ok := curAlign.extraBefore[i]; ok {
+			curExtra = n
 		}
 
-		if r == '\n' {
-			continue // Don't print the newline runes, just interpret them above.
+		tr := lineTrim(rawCol+curAlign.offset+curExtra, width, xOffset)
+		if tr.trimmed {
+			rawCol += runeWidth(r)
+			continue // Skip over characters trimmed or scrolled out of view.
+		}
+		cur = image.Point{tr.col, cur.Y}
+
+		if t.opts.wrapMode == wrapModeHScroll {
+			scrlLeft, err := t.drawScrollLeft(cvs, cur, xOffset)
+			if err != nil {
+				return err
+			}
+			scrlRight, err := t.drawScrollRight(cvs, cur, xOffset)
+			if err != nil {
+				return err
+			}
+			if scrlLeft || scrlRight {
+				rawCol++
+				continue
+			}
 		}
 
 		if i >= optRange.high { // Get the next write options.
 			optRange = t.givenWOpts.forPosition(i)
 		}
-		cells, err := cvs.SetCell(cur, r, optRange.opts.cellOpts)
+		cellOpts := optRange.opts.cellOpts
+		if t.search != nil {
+			var highlighted bool
+			searchIdx, highlighted = t.search.highlightIdx(i, searchIdx)
+			if highlighted {
+				cellOpts = append(append([]cell.Option{}, cellOpts...), t.opts.highlightCellOpts...)
+			}
+		}
+		cells, err := cvs.SetCell(cur, r, cellOpts...)
 		if err != nil {
 			return err
 		}
+		rawCol += cells
 		cur = image.Point{cur.X + cells, cur.Y} // Move within the same line.
 	}
 	return nil
@@ -224,7 +604,7 @@ func (t *Text) Draw(cvs *canvas.Canvas) error {
 	if t.newText || t.lastWidth != width {
 		// The previous text preprocessing (line wrapping) is invalidated when
 		// new text is added or the width of the canvas changed.
-		t.lines = findLines(text, width, t.opts)
+		t.lines, t.maxLineWidth = findLines(text, width, t.opts)
 	}
 	t.lastWidth = width
 
@@ -253,6 +633,18 @@ func (t *Text) Keyboard(k *terminalapi.Keyboard) error {
 		t.scroll.upOnePage()
 	case k.Key == t.opts.keyPgDown:
 		t.scroll.downOnePage()
+	case k.Key == t.opts.keyLeft:
+		t.scroll.leftOneCol()
+	case k.Key == t.opts.keyRight:
+		t.scroll.rightOneCol()
+	case k.Key == t.opts.keyHome:
+		t.scroll.homeCol()
+	case k.Key == t.opts.keyEnd:
+		t.scroll.endCol()
+	case k.Key == t.opts.keyNextMatch:
+		t.nextMatch()
+	case k.Key == t.opts.keyPrevMatch:
+		t.prevMatch()
 	}
 	return nil
 }
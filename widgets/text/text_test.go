@@ -0,0 +1,380 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mum4k/termdash/terminalapi"
+)
+
+func TestWriteMaxLinesBound(t *testing.T) {
+	txt := New(MaxLines(3))
+	for i := 0; i < 5; i++ {
+		if err := txt.Write(fmt.Sprintf("line%d\n", i)); err != nil {
+			t.Fatalf("Write => unexpected error: %v", err)
+		}
+	}
+
+	if want, got := "line2\nline3\nline4\n", txt.buff.String(); got != want {
+		t.Errorf("buff => %q, want %q", got, want)
+	}
+}
+
+func TestWriteMaxBytesBound(t *testing.T) {
+	txt := New(MaxBytes(12))
+	for i := 0; i < 5; i++ {
+		if err := txt.Write(fmt.Sprintf("line%d\n", i)); err != nil {
+			t.Fatalf("Write => unexpected error: %v", err)
+		}
+	}
+
+	if got := txt.buff.Len(); got > 12 {
+		t.Errorf("buff.Len() => %d, want <= 12", got)
+	}
+	if want, got := "line3\nline4\n", txt.buff.String(); got != want {
+		t.Errorf("buff => %q, want %q", got, want)
+	}
+}
+
+func TestSetMaxLinesEnforcesBoundImmediately(t *testing.T) {
+	txt := New()
+	for i := 0; i < 5; i++ {
+		if err := txt.Write(fmt.Sprintf("line%d\n", i)); err != nil {
+			t.Fatalf("Write => unexpected error: %v", err)
+		}
+	}
+
+	txt.SetMaxLines(2)
+	if want, got := "line3\nline4\n", txt.buff.String(); got != want {
+		t.Errorf("buff => %q, want %q", got, want)
+	}
+}
+
+func TestWriteMaxLinesRebasesGivenWOpts(t *testing.T) {
+	txt := New(MaxLines(1))
+	if err := txt.Write("dropped\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+	if err := txt.Write("kept\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+
+	if want, got := "kept\n", txt.buff.String(); got != want {
+		t.Fatalf("buff => %q, want %q", got, want)
+	}
+	if got := txt.givenWOpts.forPosition(0); got.low != 0 || got.high != len("kept\n") {
+		t.Errorf("givenWOpts.forPosition(0) => %+v, want a range covering [0, %d)", got, len("kept\n"))
+	}
+}
+
+func TestWriteMaxLinesPreservesScrollPositionRelativeToContent(t *testing.T) {
+	txt := New(MaxLines(3))
+	for i := 0; i < 3; i++ {
+		if err := txt.Write(fmt.Sprintf("line%d\n", i)); err != nil {
+			t.Fatalf("Write => unexpected error: %v", err)
+		}
+	}
+
+	// Scroll to the top line and disable following explicitly.
+	txt.scroll.firstLn = 0
+	txt.scroll.disableFollow()
+
+	// One more line arrives, pushing out the line currently at the top.
+	if err := txt.Write("line3\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+
+	if got := txt.scroll.firstLn; got != 0 {
+		t.Errorf("scroll.firstLn => %d, want 0 (clamped, the viewed line no longer exists)", got)
+	}
+}
+
+// TestWriteMaxLinesRebasesScrollAcrossWrappedEviction covers the
+// interaction of MaxLines with Wrap: a single raw line that has wrapped
+// onto multiple visual lines must shift the scroll position by the number
+// of visual lines it occupied when evicted, not by one raw line.
+func TestWriteMaxLinesRebasesScrollAcrossWrappedEviction(t *testing.T) {
+	txt := New(Wrap(), MaxLines(3))
+	// "AAAAABBBBB\n" wraps onto two visual lines at width 5: "AAAAA" and
+	// "BBBBB".
+	for _, line := range []string{"AAAAABBBBB\n", "CCCCC\n", "DDDDD\n"} {
+		if err := txt.Write(line); err != nil {
+			t.Fatalf("Write => unexpected error: %v", err)
+		}
+	}
+	// Simulates the first four visual lines a prior Draw(width=5) would have
+	// computed: "AAAAA" (0), "BBBBB" (5), "CCCCC" (11), "DDDDD" (17); the empty
+	// line findLines also starts after the final '\n' (23) is left out.
+	txt.lines = []int{0, 5, 11, 17}
+
+	// Scroll to the start of "CCCCC" and disable following explicitly.
+	txt.scroll.firstLn = 2
+	txt.scroll.disableFollow()
+
+	// A 4th raw line arrives, evicting "AAAAABBBBB\n", the oldest raw line,
+	// which occupied two of the visual lines above.
+	if err := txt.Write("EEEEE\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+
+	if want, got := "CCCCC\nDDDDD\nEEEEE\n", txt.buff.String(); got != want {
+		t.Fatalf("buff => %q, want %q", got, want)
+	}
+	if got := txt.scroll.firstLn; got != 0 {
+		t.Errorf("scroll.firstLn => %d, want 0 (still at the start of \"CCCCC\", the line it was scrolled to)", got)
+	}
+}
+
+// TestTextHScrollKeyboardAndAccessors exercises HScroll, MaxHScroll and the
+// keyLeft/keyRight/keyHome/keyEnd keyboard bindings together, since none of
+// them are driven through Text itself anywhere else.
+func TestTextHScrollKeyboardAndAccessors(t *testing.T) {
+	txt := New(HScroll())
+	// Simulates the horizontal bookkeeping a prior Draw(width=10) would have
+	// established for a widest line of 20 cells.
+	txt.maxLineWidth = 20
+	txt.scroll.hOffset(txt.maxLineWidth, 10)
+
+	if got, want := txt.HScroll(), 0; got != want {
+		t.Fatalf("HScroll => %d, want %d", got, want)
+	}
+	if got, want := txt.MaxHScroll(), 10; got != want {
+		t.Fatalf("MaxHScroll => %d, want %d", got, want)
+	}
+
+	if err := txt.Keyboard(&terminalapi.Keyboard{Key: txt.opts.keyRight}); err != nil {
+		t.Fatalf("Keyboard(keyRight) => unexpected error: %v", err)
+	}
+	if got, want := txt.HScroll(), 1; got != want {
+		t.Errorf("HScroll => %d, want %d after keyRight", got, want)
+	}
+
+	if err := txt.Keyboard(&terminalapi.Keyboard{Key: txt.opts.keyEnd}); err != nil {
+		t.Fatalf("Keyboard(keyEnd) => unexpected error: %v", err)
+	}
+	if got, want := txt.HScroll(), txt.MaxHScroll(); got != want {
+		t.Errorf("HScroll => %d, want %d after keyEnd", got, want)
+	}
+
+	if err := txt.Keyboard(&terminalapi.Keyboard{Key: txt.opts.keyLeft}); err != nil {
+		t.Fatalf("Keyboard(keyLeft) => unexpected error: %v", err)
+	}
+	if got, want := txt.HScroll(), txt.MaxHScroll()-1; got != want {
+		t.Errorf("HScroll => %d, want %d after keyLeft", got, want)
+	}
+
+	if err := txt.Keyboard(&terminalapi.Keyboard{Key: txt.opts.keyHome}); err != nil {
+		t.Fatalf("Keyboard(keyHome) => unexpected error: %v", err)
+	}
+	if got, want := txt.HScroll(), 0; got != want {
+		t.Errorf("HScroll => %d, want %d after keyHome", got, want)
+	}
+}
+
+// TestTextScrollAccessors exercises Scroll, MaxScroll and BottomScroll.
+func TestTextScrollAccessors(t *testing.T) {
+	txt := New()
+	for i := 0; i < 10; i++ {
+		if err := txt.Write(fmt.Sprintf("line%d\n", i)); err != nil {
+			t.Fatalf("Write => unexpected error: %v", err)
+		}
+	}
+	// Establishes the bookkeeping of a prior Draw(height=3) over 10 visual
+	// lines, one per line just written. A real Draw would count 11, as
+	// findLines also starts an empty line after the final '\n'.
+	txt.scroll.firstLine(10, 3)
+
+	if got, want := txt.Scroll(), 0; got != want {
+		t.Fatalf("Scroll => %d, want %d", got, want)
+	}
+	if got, want := txt.MaxScroll(), 7; got != want {
+		t.Fatalf("MaxScroll => %d, want %d", got, want)
+	}
+	if got, want := txt.BottomScroll(), txt.MaxScroll(); got != want {
+		t.Errorf("BottomScroll => %d, want %d, should equal MaxScroll", got, want)
+	}
+
+	if err := txt.Keyboard(&terminalapi.Keyboard{Key: txt.opts.keyEnd}); err != nil {
+		t.Fatalf("Keyboard(keyEnd) => unexpected error: %v", err)
+	}
+	// keyEnd only scrolls horizontally; vertical position is unaffected.
+	if got, want := txt.Scroll(), 0; got != want {
+		t.Errorf("Scroll => %d, want %d after keyEnd", got, want)
+	}
+}
+
+// TestTextSearch exercises Search, NextMatch, PrevMatch, MatchCount and
+// CurrentMatch together on a Text widget, rather than driving searchState
+// directly.
+func TestTextSearch(t *testing.T) {
+	txt := New()
+	if err := txt.Write("foo bar foo baz foo\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+
+	if err := txt.Search("foo"); err != nil {
+		t.Fatalf("Search => unexpected error: %v", err)
+	}
+	if got, want := txt.MatchCount(), 3; got != want {
+		t.Fatalf("MatchCount => %d, want %d", got, want)
+	}
+	if got, want := txt.CurrentMatch(), 1; got != want {
+		t.Errorf("CurrentMatch => %d, want %d (the first match is selected)", got, want)
+	}
+
+	txt.NextMatch()
+	if got, want := txt.CurrentMatch(), 2; got != want {
+		t.Errorf("CurrentMatch => %d, want %d after NextMatch", got, want)
+	}
+
+	txt.NextMatch()
+	txt.NextMatch() // Wraps back around to the first match.
+	if got, want := txt.CurrentMatch(), 1; got != want {
+		t.Errorf("CurrentMatch => %d, want %d after wrapping past the last match", got, want)
+	}
+
+	txt.PrevMatch() // Wraps back around to the last match.
+	if got, want := txt.CurrentMatch(), 3; got != want {
+		t.Errorf("CurrentMatch => %d, want %d after PrevMatch wraps backwards", got, want)
+	}
+}
+
+// TestTextSearchInvalidPattern verifies that Search surfaces an invalid
+// pattern as an error and leaves no active search behind.
+func TestTextSearchInvalidPattern(t *testing.T) {
+	txt := New()
+	if err := txt.Write("foo\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+
+	if err := txt.Search("("); err == nil {
+		t.Fatalf("Search => nil error, want an error for an invalid regexp")
+	}
+	if got, want := txt.MatchCount(), 0; got != want {
+		t.Errorf("MatchCount => %d, want %d after a failed Search", got, want)
+	}
+}
+
+// TestTextSearchNoMatches verifies MatchCount and CurrentMatch when the
+// pattern isn't found anywhere in the text.
+func TestTextSearchNoMatches(t *testing.T) {
+	txt := New()
+	if err := txt.Write("foo\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+
+	if err := txt.Search("bar"); err != nil {
+		t.Fatalf("Search => unexpected error: %v", err)
+	}
+	if got, want := txt.MatchCount(), 0; got != want {
+		t.Errorf("MatchCount => %d, want %d", got, want)
+	}
+	if got, want := txt.CurrentMatch(), 0; got != want {
+		t.Errorf("CurrentMatch => %d, want %d (no match selected)", got, want)
+	}
+
+	// NextMatch and PrevMatch are no-ops without any matches.
+	txt.NextMatch()
+	txt.PrevMatch()
+	if got, want := txt.CurrentMatch(), 0; got != want {
+		t.Errorf("CurrentMatch => %d, want %d after NextMatch/PrevMatch with no matches", got, want)
+	}
+}
+
+// TestTextSearchRefreshedByWrite verifies that text written after Search is
+// automatically searched too.
+func TestTextSearchRefreshedByWrite(t *testing.T) {
+	txt := New()
+	if err := txt.Write("foo\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+	if err := txt.Search("foo"); err != nil {
+		t.Fatalf("Search => unexpected error: %v", err)
+	}
+	if got, want := txt.MatchCount(), 1; got != want {
+		t.Fatalf("MatchCount => %d, want %d", got, want)
+	}
+
+	if err := txt.Write("foo\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+	if got, want := txt.MatchCount(), 2; got != want {
+		t.Errorf("MatchCount => %d, want %d after writing more matching text", got, want)
+	}
+}
+
+// TestTextClearSearch verifies that ClearSearch removes the active search.
+func TestTextClearSearch(t *testing.T) {
+	txt := New()
+	if err := txt.Write("foo\n"); err != nil {
+		t.Fatalf("Write => unexpected error: %v", err)
+	}
+	if err := txt.Search("foo"); err != nil {
+		t.Fatalf("Search => unexpected error: %v", err)
+	}
+
+	txt.ClearSearch()
+	if got, want := txt.MatchCount(), 0; got != want {
+		t.Errorf("MatchCount => %d, want %d after ClearSearch", got, want)
+	}
+	if got, want := txt.CurrentMatch(), 0; got != want {
+		t.Errorf("CurrentMatch => %d, want %d after ClearSearch", got, want)
+	}
+
+	// NextMatch and PrevMatch are no-ops once the search is cleared.
+	txt.NextMatch()
+	txt.PrevMatch()
+	if got, want := txt.CurrentMatch(), 0; got != want {
+		t.Errorf("CurrentMatch => %d, want %d after NextMatch/PrevMatch with no active search", got, want)
+	}
+}
+
+// TestWriteMaxLinesStress writes tens of thousands of short lines into a bounded
+// widget and verifies the buffer never grows past the configured bound,
+// i.e. that the widget is safe to use as an indefinite tail-style viewer.
+// maxLines is in the thousands, not the tens, so that most of the run
+// exercises repeatedly evicting from a buffer that's actually at its bound,
+// rather than a tiny buffer that every Write rebuilds from scratch anyway.
+func TestWriteMaxLinesStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	const (
+		maxLines = 5000
+		numLines = 50000
+	)
+
+	txt := New(MaxLines(maxLines))
+	for i := 0; i < numLines; i++ {
+		if err := txt.Write(fmt.Sprintf("%d\n", i)); err != nil {
+			t.Fatalf("Write => unexpected error on line %d: %v", i, err)
+		}
+	}
+
+	got := txt.buff.String()
+	if gotLines := strings.Count(got, "\n"); gotLines != maxLines {
+		t.Errorf("after stress writes, buff contains %d lines, want %d", gotLines, maxLines)
+	}
+	if want := fmt.Sprintf("%d\n", numLines-1); !strings.HasSuffix(got, want) {
+		t.Errorf("after stress writes, buff doesn't end with the most recent line %q", want)
+	}
+	if want := fmt.Sprintf("%d\n", numLines-maxLines); !strings.HasPrefix(got, want) {
+		t.Errorf("after stress writes, buff doesn't start with the oldest retained line %q", want)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"github.com/mum4k/termdash/cell"
+)
+
+// WriteOption is used to provide options to Write().
+type WriteOption interface {
+	// set sets the provided option.
+	set(*writeOptions)
+}
+
+// writeOption implements WriteOption.
+type writeOption func(*writeOptions)
+
+// set implements WriteOption.set.
+func (wo writeOption) set(opts *writeOptions) {
+	wo(opts)
+}
+
+// writeOptions stores the options provided to Write().
+type writeOptions struct {
+	cellOpts []cell.Option
+}
+
+// newWriteOptions returns a new writeOptions instance with the provided
+// options applied on top of the zero value.
+func newWriteOptions(wOpts ...WriteOption) writeOptions {
+	var wo writeOptions
+	for _, opt := range wOpts {
+		opt.set(&wo)
+	}
+	return wo
+}
+
+// WriteCellOpts sets the cell options for the text provided in this call to
+// Write.
+func WriteCellOpts(cOpts ...cell.Option) WriteOption {
+	return writeOption(func(opts *writeOptions) {
+		opts.cellOpts = cOpts
+	})
+}
+
+// optsRange applies writeOptions to the range of bytes [low, high) in the
+// text buffer.
+type optsRange struct {
+	low  int
+	high int
+	opts writeOptions
+}
+
+// newOptsRange returns a new optsRange for the provided range and options.
+func newOptsRange(low, high int, opts writeOptions) optsRange {
+	return optsRange{
+		low:  low,
+		high: high,
+		opts: opts,
+	}
+}
+
+// givenWOpts tracks the write options given to each Write call, keyed by the
+// starting byte position of the range the options apply to.
+type givenWOpts map[int]optsRange
+
+// newGivenWOpts returns a new, empty givenWOpts.
+func newGivenWOpts() givenWOpts {
+	return givenWOpts{}
+}
+
+// forPosition returns the optsRange that applies to the byte at position
+// pos. Returns the zero value if no options were given for that position.
+func (g givenWOpts) forPosition(pos int) optsRange {
+	var (
+		found    optsRange
+		foundLow = -1
+	)
+	for low, r := range g {
+		if low <= pos && pos < r.high && low > foundLow {
+			found = r
+			foundLow = low
+		}
+	}
+	return found
+}
+
+// rebase returns a copy of g with every range shifted back by drop bytes, as
+// needed after drop leading bytes were discarded from the text buffer, e.g.
+// by the ring buffer bounds. A range falling entirely within the dropped
+// bytes is itself dropped, and one straddling the cut is clipped to start at
+// the new beginning of the buffer.
+func (g givenWOpts) rebase(drop int) givenWOpts {
+	rebased := newGivenWOpts()
+	if drop <= 0 {
+		for low, r := range g {
+			rebased[low] = r
+		}
+		return rebased
+	}
+
+	for _, r := range g {
+		if r.high <= drop {
+			continue // Entirely within the dropped bytes.
+		}
+		low := r.low - drop
+		if low < 0 {
+			low = 0
+		}
+		rebased[low] = newOptsRange(low, r.high-drop, r.opts)
+	}
+	return rebased
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import "testing"
+
+func TestGivenWOptsRebase(t *testing.T) {
+	newGiven := func() givenWOpts {
+		g := newGivenWOpts()
+		g[0] = newOptsRange(0, 5, writeOptions{})
+		g[5] = newOptsRange(5, 10, writeOptions{})
+		g[10] = newOptsRange(10, 15, writeOptions{})
+		return g
+	}
+
+	tests := []struct {
+		desc string
+		drop int
+		want map[int]optsRange
+	}{
+		{
+			desc: "no-op when nothing is dropped",
+			drop: 0,
+			want: map[int]optsRange{
+				0:  newOptsRange(0, 5, writeOptions{}),
+				5:  newOptsRange(5, 10, writeOptions{}),
+				10: newOptsRange(10, 15, writeOptions{}),
+			},
+		},
+		{
+			desc: "drop point lands exactly on a range boundary",
+			drop: 5,
+			want: map[int]optsRange{
+				0: newOptsRange(0, 5, writeOptions{}),
+				5: newOptsRange(5, 10, writeOptions{}),
+			},
+		},
+		{
+			desc: "drop point straddles a range, which is clipped",
+			drop: 7,
+			want: map[int]optsRange{
+				0: newOptsRange(0, 3, writeOptions{}),
+				3: newOptsRange(3, 8, writeOptions{}),
+			},
+		},
+		{
+			desc: "drop removes every range",
+			drop: 15,
+			want: map[int]optsRange{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := newGiven().rebase(tc.drop)
+			if len(got) != len(tc.want) {
+				t.Fatalf("rebase(%d) => %v, want %v", tc.drop, got, tc.want)
+			}
+			for low, want := range tc.want {
+				if g := got[low]; g.low != want.low || g.high != want.high {
+					t.Errorf("rebase(%d)[%d] => %+v, want %+v", tc.drop, low, g, want)
+				}
+			}
+		})
+	}
+}